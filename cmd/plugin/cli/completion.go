@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Lc-Lin/kubectl-triage/pkg/plugin"
+	"github.com/spf13/cobra"
+)
+
+// completionTimeout bounds cluster lookups made while completing, so a slow
+// API server doesn't hang the shell
+const completionTimeout = 5 * time.Second
+
+// kubectlCompletionBinary is the name kubectl looks up in PATH to complete
+// "kubectl triage ..."; link it to kubectl-triage to enable that
+const kubectlCompletionBinary = "kubectl_complete-triage"
+
+// completionCmd prints the shell completion script
+func completionCmd(root *cobra.Command) *cobra.Command {
+	return &cobra.Command{
+		Use:   "completion bash|zsh|fish|powershell",
+		Short: "Print the shell completion script",
+		Long: `Print the completion script for kubectl-triage, including pod names from
+the cluster (unhealthy pods first) and --context and --namespace values.
+
+To complete "kubectl triage ..." through kubectl instead (kubectl 1.26+), link
+` + kubectlCompletionBinary + ` to kubectl-triage somewhere in your PATH.`,
+		Example: `  # bash
+  source <(kubectl-triage completion bash)
+
+  # zsh
+  kubectl-triage completion zsh > "${fpath[1]}/_kubectl-triage"
+
+  # fish
+  kubectl-triage completion fish > ~/.config/fish/completions/kubectl-triage.fish
+
+  # PowerShell
+  kubectl-triage completion powershell | Out-String | Invoke-Expression
+
+  # kubectl triage <TAB>
+  ln -s "$(command -v kubectl-triage)" "$(dirname "$(command -v kubectl-triage)")/` + kubectlCompletionBinary + `"`,
+		Args:      cobra.ExactValidArgs(1),
+		ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch args[0] {
+			case "bash":
+				return root.GenBashCompletion(os.Stdout)
+			case "zsh":
+				return root.GenZshCompletion(os.Stdout)
+			case "fish":
+				return root.GenFishCompletion(os.Stdout, true)
+			case "powershell":
+				return root.GenPowerShellCompletionWithDesc(os.Stdout)
+			}
+			return fmt.Errorf("unsupported shell %q", args[0])
+		},
+	}
+}
+
+// registerCompletions adds dynamic completion of the pod names and the
+// --context and --namespace values
+func registerCompletions(cmd *cobra.Command) {
+	completionOpts := func() *plugin.TriageOptions {
+		return &plugin.TriageOptions{Timeout: completionTimeout}
+	}
+
+	cmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		names, err := plugin.CompletePodNames(context.Background(), KubernetesConfigFlags, completionOpts(), toComplete)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		// Pods already named aren't offered again
+		given := map[string]bool{}
+		for _, arg := range args {
+			given[arg] = true
+		}
+		var completions []string
+		for _, name := range names {
+			if !given[name] {
+				completions = append(completions, name)
+			}
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	cmd.RegisterFlagCompletionFunc("namespace", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		names, err := plugin.CompleteNamespaces(context.Background(), KubernetesConfigFlags, completionOpts(), toComplete)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	cmd.RegisterFlagCompletionFunc("context", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		names, err := plugin.CompleteContexts(KubernetesConfigFlags, toComplete)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	})
+}
@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Lc-Lin/kubectl-triage/pkg/plugin"
+	"github.com/manifoldco/promptui"
+	"github.com/mattn/go-isatty"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+// pickPod chooses the pod to triage when none was named: interactively on a
+// terminal, otherwise it fails with the list of candidates
+func pickPod(ctx context.Context, configFlags *genericclioptions.ConfigFlags, opts *plugin.TriageOptions) (string, error) {
+	candidates, namespace, err := plugin.UnhealthyPods(ctx, configFlags, opts)
+	if err != nil {
+		return "", err
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no pod name given, and no unhealthy pods in namespace %s", namespace)
+	}
+
+	labels := plugin.CandidateLabels(candidates)
+	if !isInteractive() {
+		return "", fmt.Errorf("no pod name given; unhealthy pods in namespace %s:\n  %s", namespace, strings.Join(labels, "\n  "))
+	}
+
+	prompt := promptui.Select{
+		Label: fmt.Sprintf("Unhealthy pods in %s (type / to search)", namespace),
+		Items: labels,
+		Size:  15,
+		Searcher: func(input string, index int) bool {
+			return plugin.FuzzyMatch(input, candidates[index].Name)
+		},
+	}
+	index, _, err := prompt.Run()
+	if err != nil {
+		return "", fmt.Errorf("no pod selected: %v", err)
+	}
+	return candidates[index].Name, nil
+}
+
+// isInteractive reports whether both stdin and stdout are terminals
+func isInteractive() bool {
+	return isatty.IsTerminal(os.Stdin.Fd()) && isatty.IsTerminal(os.Stdout.Fd())
+}
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/Lc-Lin/kubectl-triage/pkg/plugin"
+	"github.com/spf13/cobra"
+)
+
+// rbacCmd prints the Role with the minimal permissions the triage needs
+func rbacCmd() *cobra.Command {
+	var (
+		namespace string
+		name      string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "rbac",
+		Short: "Print the minimal Role needed to triage pods in a namespace",
+		Long: `Print a Role with the permissions kubectl-triage uses in a namespace: get and
+list pods, get pods/log and list events. Without pods/log or events the
+triage still runs, skipping those sections with a note.`,
+		Example: `  # Create the Role in the current namespace and bind it to a user
+  kubectl triage rbac | kubectl apply -f -
+  kubectl create rolebinding kubectl-triage --role=kubectl-triage --user=jane
+
+  # For another namespace
+  kubectl triage rbac -n production`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if namespace == "" {
+				namespace = "default"
+				if ns, _, err := KubernetesConfigFlags.ToRawKubeConfigLoader().Namespace(); err == nil && ns != "" {
+					namespace = ns
+				}
+			}
+			_, err := fmt.Fprint(cmd.OutOrStdout(), plugin.RoleYAML(name, namespace))
+			return err
+		},
+	}
+
+	cmd.Flags().StringVarP(&namespace, "namespace", "n", "", "Namespace of the Role (default: the current namespace)")
+	cmd.Flags().StringVar(&name, "name", "kubectl-triage", "Name of the Role")
+	return cmd
+}
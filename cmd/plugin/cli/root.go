@@ -1,12 +1,19 @@
 package cli
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/Lc-Lin/kubectl-triage/pkg/logger"
 	"github.com/Lc-Lin/kubectl-triage/pkg/plugin"
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
@@ -18,14 +25,40 @@ var (
 
 func RootCmd() *cobra.Command {
 	var (
-		lines         int64
-		allContainers bool
-		force         bool
-		noColor       bool
+		lines          int64
+		allContainers  bool
+		force          bool
+		noColor        bool
+		verbosity      int
+		debug          bool
+		timeout        time.Duration
+		limitBytes     int64
+		maxLineWidth   int
+		maxOutputBytes int
+		fullLines      bool
+		minLevel       string
+		rawLogs        bool
+		grep           string
+		grepBefore     int
+		grepAfter      int
+		noFold         bool
+		foldScanLines  int64
+		logKeys        plugin.LogKeys
+		configFile     string
+		redact         bool
+		failOn         string
+		selector       string
+		junitFile      string
+		output         string
+		ascii          bool
+		compact        bool
+		concurrency    int
+		qps            float32
+		burst          int
 	)
 
 	cmd := &cobra.Command{
-		Use:   "kubectl-triage <pod-name>",
+		Use:   "kubectl-triage [pod-name... | -l selector]",
 		Short: "Fast triage for failed Kubernetes pods",
 		Long: `kubectl-triage provides a 5-second diagnostic snapshot for failed Kubernetes pods.
 
@@ -35,10 +68,18 @@ It intelligently aggregates:
   - Previous crash logs (if container restarted)
   - Current container logs
 
-Only failed/restarted containers are shown by default, keeping output focused.`,
+Only failed/restarted containers are shown by default, keeping output focused.
+
+Without a pod name, the unhealthy pods in the namespace are offered in an
+interactive picker, most recent failure first. Several pod names, or
+--selector, triage several pods in parallel, e.g. as a gate in a CI/CD
+pipeline.`,
 		Example: `  # Triage a crashing pod
   kubectl triage my-failing-pod
 
+  # Pick from the unhealthy pods in a namespace
+  kubectl triage -n production
+
   # Triage a pod in a specific namespace
   kubectl triage my-pod -n production
 
@@ -49,36 +90,158 @@ Only failed/restarted containers are shown by default, keeping output focused.`,
   kubectl triage my-pod --force
 
   # Show more log lines
-  kubectl triage my-pod --lines=100`,
+  kubectl triage my-pod --lines=100
+
+  # Show log lines in full, even minified JSON
+  kubectl triage my-pod --full-lines
+
+  # Only show warnings and errors from JSON/logfmt logs
+  kubectl triage my-pod --min-level=warn
+
+  # Search the whole current and previous logs, with 3 lines of context
+  kubectl triage my-pod --grep='connection refused' -A 3 -B 3
+
+  # Show repeated log lines one by one instead of folding them
+  kubectl triage my-pod --no-fold
+
+  # Mask tokens, passwords and emails before pasting the output
+  kubectl triage my-pod --redact
+
+  # Gate a deploy pipeline: exit non-zero only if a container is failing
+  kubectl triage my-pod --fail-on=error
+
+  # Triage several pods at once; their output comes in the order given
+  kubectl triage api-7d8f9c-xkz2q worker-5c6d7e-p9q8r billing-0
+
+  # Triage every pod of an app and write a JUnit report for CI
+  kubectl triage --selector app=checkout --junit=report.xml
+
+  # Paste-ready incident notes, redacted by default
+  kubectl triage my-pod -o markdown
+  kubectl triage my-pod -o slack
+
+  # A self-contained HTML report to attach to a postmortem
+  kubectl triage my-pod -o html > triage.html
+
+  # One-line summaries for a dashboard or chatbot
+  kubectl triage -l app=checkout -o go-template='{{range .Pods}}{{.Name}} {{.Status}}{{"\n"}}{{end}}'
+  kubectl triage -l app=checkout -o custom-columns=POD:.Name,STATUS:.Status,RESTARTS:.Restarts
+
+  # A short, emoji-free triage for a narrow terminal or a log viewer
+  kubectl triage my-pod --compact --ascii
+
+  # Give a slow API server more time
+  kubectl triage my-pod --timeout=1m
+
+  # See which API calls a slow triage is waiting on
+  kubectl triage my-pod -v`,
 		SilenceErrors: true,
 		SilenceUsage:  true,
-		Args:          cobra.ExactArgs(1), // Require exactly one argument (pod name)
+		Args:          cobra.ArbitraryArgs, // The pod names, picked interactively when omitted
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			// Diagnostics go to stderr, so they never mix with redirected output
+			level := logger.Level(verbosity)
+			if debug {
+				level = logger.LevelDebug
+			}
+			logger.SetLevel(level)
+			logger.ConfigureColor(os.Stdout, noColor)
+		},
 		PreRun: func(cmd *cobra.Command, args []string) {
 			viper.BindPFlags(cmd.Flags())
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// Get pod name from arguments
-			podName := args[0]
-
 			// Get namespace from kubectl flags
 			namespace := ""
 			if KubernetesConfigFlags.Namespace != nil && *KubernetesConfigFlags.Namespace != "" {
 				namespace = *KubernetesConfigFlags.Namespace
 			}
 
+			// Load highlight rules, failure reasons and ignored containers
+			configFiles, err := plugin.ConfigFiles(configFile)
+			if err != nil {
+				return err
+			}
+			config, err := plugin.LoadConfig(configFiles)
+			if err != nil {
+				return err
+			}
+
+			// --grep searches the whole log unless --limit-bytes is given, as the
+			// cap keeps the start of a log and would leave the newest lines out
+			if grep != "" && !cmd.Flags().Changed("limit-bytes") {
+				limitBytes = 0
+			}
+
+			// Reports meant for sharing are redacted unless --redact=false is given
+			if !cmd.Flags().Changed("redact") && plugin.RedactsByDefault(output, junitFile) {
+				redact = true
+			}
+
 			// Build triage options
 			opts := &plugin.TriageOptions{
-				PodName:       podName,
-				Namespace:     namespace,
-				Lines:         lines,
-				AllContainers: allContainers,
-				Force:         force,
-				NoColor:       noColor,
+				Namespace:      namespace,
+				Lines:          lines,
+				AllContainers:  allContainers,
+				Force:          force,
+				NoColor:        color.NoColor,
+				Timeout:        timeout,
+				LimitBytes:     limitBytes,
+				MaxLineWidth:   maxLineWidth,
+				MaxOutputBytes: maxOutputBytes,
+				FullLines:      fullLines,
+				MinLevel:       minLevel,
+				RawLogs:        rawLogs,
+				Grep:           grep,
+				GrepBefore:     grepBefore,
+				GrepAfter:      grepAfter,
+				NoFold:         noFold,
+				FoldScanLines:  foldScanLines,
+				LogKeys:        logKeys,
+				Config:         config,
+				Redact:         redact,
+				FailOn:         failOn,
+				Selector:       selector,
+				JUnitFile:      junitFile,
+				Output:         output,
+				Width:          logger.TerminalWidth(os.Stdout),
+				ASCII:          ascii,
+				Compact:        compact,
+				Concurrency:    concurrency,
+				QPS:            qps,
+				Burst:          burst,
+			}
+
+			// Cancel on Ctrl-C so partial results still get rendered; a second
+			// Ctrl-C falls through to the default handler and exits immediately
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			go func() {
+				<-ctx.Done()
+				stop()
+			}()
+
+			// Offer the unhealthy pods when no pod was named
+			if selector != "" {
+				if len(args) > 0 {
+					return fmt.Errorf("a pod name and --selector can't be combined")
+				}
+			} else if len(args) > 0 {
+				opts.PodName, opts.PodNames = args[0], args[1:]
+			} else {
+				podName, err := pickPod(ctx, KubernetesConfigFlags, opts)
+				if err != nil {
+					return err
+				}
+				opts.PodName = podName
 			}
 
 			// Run the triage
-			if err := plugin.RunPlugin(KubernetesConfigFlags, opts); err != nil {
-				return errors.Unwrap(err)
+			if err := plugin.RunPlugin(ctx, KubernetesConfigFlags, opts); err != nil {
+				if unwrapped := errors.Unwrap(err); unwrapped != nil {
+					return unwrapped
+				}
+				return err
 			}
 
 			return nil
@@ -95,16 +258,63 @@ Only failed/restarted containers are shown by default, keeping output focused.`,
 	cmd.Flags().Int64Var(&lines, "lines", 50, "Number of log lines to display (default: 50)")
 	cmd.Flags().BoolVar(&allContainers, "all-containers", false, "Show all containers, not just failed/restarted ones")
 	cmd.Flags().BoolVar(&force, "force", false, "Inspect pod even if it appears healthy")
-	cmd.Flags().BoolVar(&noColor, "no-color", false, "Disable colored output")
+	cmd.Flags().Int64Var(&limitBytes, "limit-bytes", plugin.DefaultLimitBytes, "Maximum bytes fetched per log stream (0 for no limit; none with --grep unless given)")
+	cmd.Flags().IntVar(&maxLineWidth, "max-line-width", plugin.DefaultMaxLineWidth, "Cut log lines longer than this many characters (0 for no limit)")
+	cmd.Flags().IntVar(&maxOutputBytes, "max-output-bytes", plugin.DefaultMaxOutputBytes, "Maximum bytes of log text printed across all containers (0 for no limit)")
+	cmd.Flags().BoolVar(&fullLines, "full-lines", false, "Print log lines in full, ignoring --max-line-width and --max-output-bytes")
+	cmd.Flags().StringVar(&minLevel, "min-level", "", "Hide JSON/logfmt log lines below this level (debug, info, warn, error, fatal)")
+	cmd.Flags().BoolVar(&rawLogs, "raw-logs", false, "Print JSON/logfmt log lines as-is instead of a compact form")
+	cmd.Flags().StringVar(&grep, "grep", "", "Only show log lines matching this regular expression, searching the whole current and previous logs")
+	cmd.Flags().IntVarP(&grepAfter, "after-context", "A", 0, "Lines of context shown after each --grep match")
+	cmd.Flags().IntVarP(&grepBefore, "before-context", "B", 0, "Lines of context shown before each --grep match")
+	cmd.Flags().BoolVar(&noFold, "no-fold", false, "Show repeated log lines one by one instead of folding them into \"[xN] line\"")
+	cmd.Flags().Int64Var(&foldScanLines, "fold-scan-lines", plugin.DefaultFoldScanLines, "Log lines fetched per stream when folding, so --lines still shows distinct content")
+	cmd.Flags().StringSliceVar(&logKeys.Level, "level-key", nil, "Extra field names holding the level in JSON/logfmt logs")
+	cmd.Flags().StringSliceVar(&logKeys.Message, "message-key", nil, "Extra field names holding the message in JSON/logfmt logs")
+	cmd.Flags().StringSliceVar(&logKeys.Error, "error-key", nil, "Extra field names holding the error in JSON/logfmt logs")
+	cmd.Flags().StringSliceVar(&logKeys.Time, "time-key", nil, "Extra field names holding the timestamp in JSON/logfmt logs")
+	cmd.Flags().StringVar(&failOn, "fail-on", plugin.FailOnWarning, "Least severe outcome that exits non-zero: warning (also degraded and pending pods) or error (failing pods only)")
+	cmd.Flags().StringVarP(&output, "output", "o", plugin.OutputText, "Output format: text, markdown, slack, html, go-template=..., go-template-file=... or custom-columns=...; all but text are redacted unless --redact=false")
+	cmd.Flags().BoolVar(&ascii, "ascii", false, "Leave emoji and other symbols out of the text output, for terminals and log viewers that can't show them")
+	cmd.Flags().BoolVar(&compact, "compact", false, "Fit each failed container's text output into about 25 lines, cut to the terminal width")
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "Triage every pod matching this label selector, e.g. app=checkout")
+	cmd.Flags().StringVar(&junitFile, "junit", "", "Write a JUnit XML report with a test case per container to this file (redacted unless --redact=false)")
+	cmd.Flags().BoolVar(&redact, "redact", false, "Mask secrets and PII (tokens, passwords, keys, emails) in logs and events")
+	cmd.Flags().StringVar(&configFile, "config", os.Getenv("KUBECTL_TRIAGE_CONFIG"), "Extra config file, read after ~/.kube/triage.yaml and the nearest .triage.yaml (env KUBECTL_TRIAGE_CONFIG)")
+	cmd.Flags().IntVar(&concurrency, "concurrency", plugin.DefaultConcurrency, "Pods triaged, and API requests kept in flight, at a time")
+	cmd.Flags().Float32Var(&qps, "qps", plugin.DefaultQPS, "Maximum API requests per second")
+	cmd.Flags().IntVar(&burst, "burst", plugin.DefaultBurst, "Maximum burst of API requests above --qps")
+	cmd.Flags().DurationVar(&timeout, "timeout", 30*time.Second, "Maximum time for the whole triage; partial results are shown when it expires (0 for no limit)")
+
+	cmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output (also NO_COLOR; off when stdout isn't a terminal)")
+	cmd.PersistentFlags().CountVarP(&verbosity, "verbose", "v", "Report progress, each API call and a timing breakdown on stderr; -vv adds debug details")
+	cmd.PersistentFlags().BoolVar(&debug, "debug", false, "Same as -vv")
+
+	registerCompletions(cmd)
+	cmd.AddCommand(completionCmd(cmd))
+	cmd.AddCommand(rbacCmd())
 
 	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 	return cmd
 }
 
 func InitAndExecute() {
-	if err := RootCmd().Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	cmd := RootCmd()
+
+	// kubectl completes "kubectl triage ..." by running kubectl_complete-triage
+	// with the words typed so far, expecting cobra's __complete output
+	if filepath.Base(os.Args[0]) == kubectlCompletionBinary {
+		cmd.SetArgs(append([]string{cobra.ShellCompRequestCmd}, os.Args[1:]...))
+	}
+
+	if err := cmd.Execute(); err != nil {
+		// Failing, degraded and pending pods are explained by the triage output
+		// itself; only the exit code tells scripts the outcome
+		var outcome *plugin.OutcomeError
+		if !errors.As(err, &outcome) || !outcome.Reported() {
+			logger.NewLogger().ErrorMsg("Error: %v", err)
+		}
+		os.Exit(plugin.ExitCode(err))
 	}
 }
 
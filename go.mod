@@ -5,10 +5,12 @@ go 1.16
 require (
 	github.com/fatih/color v1.7.0
 	github.com/manifoldco/promptui v0.3.2
+	github.com/mattn/go-isatty v0.0.7
 	github.com/nicksnyder/go-i18n v1.10.3 // indirect
 	github.com/replicatedhq/krew-plugin-template v0.0.0-20210720150039-35bbe5614764
-	github.com/spf13/cobra v0.0.4
-	github.com/spf13/viper v1.4.0
+	github.com/spf13/cobra v1.1.3
+	github.com/spf13/viper v1.7.0
+	golang.org/x/term v0.0.0-20201210144234-2321bbc49cbf
 	gopkg.in/alecthomas/kingpin.v3-unstable v3.0.0-20191105091915-95d230a53780 // indirect
 	k8s.io/api v0.0.0-20190313235455-40a48860b5ab
 	k8s.io/apimachinery v0.0.0-20190313205120-d7deff9243b1
@@ -2,39 +2,199 @@ package logger
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"strconv"
 
 	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+	"golang.org/x/term"
 )
 
+// Level is how much a Logger reports
+type Level int
+
+const (
+	// LevelNormal reports output, warnings and errors
+	LevelNormal Level = iota
+	// LevelVerbose adds progress notes (-v)
+	LevelVerbose
+	// LevelDebug adds details for troubleshooting the plugin itself (-vv)
+	LevelDebug
+)
+
+// Logger writes output to Out and diagnostics (warnings, errors, verbose and
+// debug messages) to Err, so diagnostics never end up in redirected output
 type Logger struct {
+	// Out and Err default to os.Stdout and os.Stderr when nil
+	Out   io.Writer
+	Err   io.Writer
+	Level Level
+}
+
+// Defaults of the loggers returned by NewLogger
+var (
+	defaultOut   io.Writer
+	defaultErr   io.Writer
+	defaultLevel = LevelNormal
+)
+
+// colors are the names accepted by Colored
+var colors = map[string]color.Attribute{
+	"red":     color.FgHiRed,
+	"yellow":  color.FgHiYellow,
+	"green":   color.FgHiGreen,
+	"blue":    color.FgHiBlue,
+	"magenta": color.FgHiMagenta,
+	"cyan":    color.FgHiCyan,
+	"white":   color.FgHiWhite,
+}
+
+// IsColor reports whether name can be passed to Colored
+func IsColor(name string) bool {
+	_, ok := colors[name]
+	return ok
 }
 
+// NewLogger returns a logger with the writers and level set by SetOutput and SetLevel
 func NewLogger() *Logger {
-	return &Logger{}
+	return New(defaultOut, defaultErr, defaultLevel)
+}
+
+// New returns a logger writing output to out and diagnostics to errOut
+func New(out, errOut io.Writer, level Level) *Logger {
+	return &Logger{Out: out, Err: errOut, Level: level}
+}
+
+// SetOutput sets the writers of the loggers returned by NewLogger; nil
+// restores os.Stdout and os.Stderr
+func SetOutput(out, errOut io.Writer) {
+	defaultOut, defaultErr = out, errOut
+}
+
+// SetLevel sets the level of the loggers returned by NewLogger
+func SetLevel(level Level) {
+	defaultLevel = level
+}
+
+// ConfigureColor turns colored output on for every logger and renderer when
+// w is a terminal, unless noColor (--no-color), NO_COLOR (https://no-color.org)
+// or TERM=dumb says otherwise; it returns whether color is on
+func ConfigureColor(w io.Writer, noColor bool) bool {
+	on := !noColor && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb" && IsTerminal(w)
+	color.NoColor = !on
+	return on
+}
+
+// IsTerminal reports whether w is a terminal
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+// TerminalWidth returns the width in columns of the terminal w writes to:
+// COLUMNS when it is set, else the size of the terminal; zero when w isn't
+// a terminal, so piped output isn't laid out for a screen
+func TerminalWidth(w io.Writer) int {
+	f, ok := w.(*os.File)
+	if !ok || !IsTerminal(w) {
+		return 0
+	}
+	return terminalWidth(func() (int, error) {
+		width, _, err := term.GetSize(int(f.Fd()))
+		return width, err
+	})
+}
+
+// terminalWidth returns COLUMNS when it is set, else the width size reports
+func terminalWidth(size func() (int, error)) int {
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	width, err := size()
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
+func (l *Logger) out() io.Writer {
+	if l.Out == nil {
+		return os.Stdout
+	}
+	return l.Out
+}
+
+func (l *Logger) err() io.Writer {
+	if l.Err == nil {
+		return os.Stderr
+	}
+	return l.Err
 }
 
 func (l *Logger) Info(msg string, args ...interface{}) {
 	if msg == "" {
-		fmt.Println("")
+		fmt.Fprintln(l.out(), "")
 		return
 	}
 
 	c := color.New(color.FgHiCyan)
-	c.Println(fmt.Sprintf(msg, args...))
+	c.Fprintln(l.out(), fmt.Sprintf(msg, args...))
 }
 
+// Error writes the error message of err to Err
 func (l *Logger) Error(err error) {
 	c := color.New(color.FgHiRed)
-	c.Println(fmt.Sprintf("%#v", err))
+	c.Fprintln(l.err(), err.Error())
 }
 
 func (l *Logger) ErrorMsg(msg string, args ...interface{}) {
 	c := color.New(color.FgHiRed)
-	c.Println(fmt.Sprintf(msg, args...))
+	c.Fprintln(l.err(), fmt.Sprintf(msg, args...))
+}
+
+func (l *Logger) Warn(msg string, args ...interface{}) {
+	c := color.New(color.FgHiYellow)
+	c.Fprintln(l.err(), fmt.Sprintf(msg, args...))
+}
+
+// Verbose writes a progress note to Err at LevelVerbose and above
+func (l *Logger) Verbose(msg string, args ...interface{}) {
+	if l.Level < LevelVerbose {
+		return
+	}
+	fmt.Fprintln(l.err(), fmt.Sprintf(msg, args...))
+}
+
+// Debug writes a troubleshooting detail to Err at LevelDebug
+func (l *Logger) Debug(msg string, args ...interface{}) {
+	if l.Level < LevelDebug {
+		return
+	}
+	c := color.New(color.FgHiBlack)
+	c.Fprintln(l.err(), fmt.Sprintf(msg, args...))
+}
+
+// Colored prints in the named color, or uncolored if the name is unknown
+func (l *Logger) Colored(name string, msg string, args ...interface{}) {
+	Fprintln(l.out(), name, fmt.Sprintf(msg, args...))
 }
 
 func (l *Logger) Instructions(msg string, args ...interface{}) {
 	white := color.New(color.FgHiWhite)
-	white.Println("")
-	white.Println(fmt.Sprintf(msg, args...))
+	white.Fprintln(l.out(), "")
+	white.Fprintln(l.out(), fmt.Sprintf(msg, args...))
+}
+
+// Fprintln writes msg to w in the named color, or uncolored if the name is
+// unknown; like the Logger methods it leaves out colors when color.NoColor is set
+func Fprintln(w io.Writer, name string, msg string) (int, error) {
+	attr, ok := colors[name]
+	if !ok {
+		return fmt.Fprintln(w, msg)
+	}
+	return color.New(attr).Fprintln(w, msg)
 }
@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+// TestLoggerWriters checks that output and diagnostics go to their own writers
+func TestLoggerWriters(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	tests := []struct {
+		name    string
+		level   Level
+		log     func(l *Logger)
+		wantOut string
+		wantErr string
+	}{
+		{name: "info", log: func(l *Logger) { l.Info("pod %s", "web-1") }, wantOut: "pod web-1\n"},
+		{name: "colored", log: func(l *Logger) { l.Colored("magenta", "declined") }, wantOut: "declined\n"},
+		{name: "warn", log: func(l *Logger) { l.Warn("slow") }, wantErr: "slow\n"},
+		{name: "error", log: func(l *Logger) { l.Error(errors.New("pod not found")) }, wantErr: "pod not found\n"},
+		{name: "verbose hidden", log: func(l *Logger) { l.Verbose("listing pods") }},
+		{name: "verbose", level: LevelVerbose, log: func(l *Logger) { l.Verbose("listing pods") }, wantErr: "listing pods\n"},
+		{name: "debug hidden at verbose", level: LevelVerbose, log: func(l *Logger) { l.Debug("GET /pods") }},
+		{name: "debug", level: LevelDebug, log: func(l *Logger) { l.Debug("GET /pods") }, wantErr: "GET /pods\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			tt.log(New(&out, &errOut, tt.level))
+			if out.String() != tt.wantOut {
+				t.Errorf("out = %q, want %q", out.String(), tt.wantOut)
+			}
+			if errOut.String() != tt.wantErr {
+				t.Errorf("err = %q, want %q", errOut.String(), tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestConfigureColor checks that color stays off for --no-color and writers
+// that aren't terminals
+func TestConfigureColor(t *testing.T) {
+	noColor := color.NoColor
+	defer func() { color.NoColor = noColor }()
+
+	var buf bytes.Buffer
+	if ConfigureColor(&buf, false) || !color.NoColor {
+		t.Error("ConfigureColor() turned color on for a buffer")
+	}
+
+	if ConfigureColor(os.Stdout, true) || !color.NoColor {
+		t.Error("ConfigureColor() turned color on despite --no-color")
+	}
+}
+
+// TestTerminalWidth checks that COLUMNS wins and that a buffer has no width
+func TestTerminalWidth(t *testing.T) {
+	columns, set := os.LookupEnv("COLUMNS")
+	defer func() {
+		if set {
+			os.Setenv("COLUMNS", columns)
+		} else {
+			os.Unsetenv("COLUMNS")
+		}
+	}()
+
+	tests := []struct {
+		columns string
+		want    int
+	}{
+		{"", 120},
+		{"100", 100},
+		{"wide", 120},
+		{"-3", 120},
+	}
+	size := func() (int, error) { return 120, nil }
+	for _, tt := range tests {
+		os.Setenv("COLUMNS", tt.columns)
+		if got := terminalWidth(size); got != tt.want {
+			t.Errorf("terminalWidth() with COLUMNS=%q = %d, want %d", tt.columns, got, tt.want)
+		}
+		// Output that isn't a terminal is never laid out for one
+		if got := TerminalWidth(&bytes.Buffer{}); got != 0 {
+			t.Errorf("TerminalWidth(buffer) with COLUMNS=%q = %d, want 0", tt.columns, got)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+// PodCandidate is an unhealthy pod offered for triage when no pod name is given
+type PodCandidate struct {
+	Name     string
+	Reason   string
+	Restarts int32
+	// LastFailure is when the pod last showed a problem: a container
+	// termination or the pod turning unready
+	LastFailure time.Time
+}
+
+// Summary describes the candidate, e.g. "CrashLoopBackOff, 5 restarts, 3m ago"
+func (c PodCandidate) Summary() string {
+	summary := fmt.Sprintf("%s, %d restarts", c.Reason, c.Restarts)
+	if !c.LastFailure.IsZero() {
+		summary += fmt.Sprintf(", %s ago", formatDuration(time.Since(c.LastFailure)))
+	}
+	return summary
+}
+
+// UnhealthyPods lists the pods in the namespace that aren't truly healthy,
+// most recent failure first, along with the namespace searched
+func UnhealthyPods(ctx context.Context, configFlags *genericclioptions.ConfigFlags, opts *TriageOptions) ([]PodCandidate, string, error) {
+	pods, namespace, err := listPods(ctx, configFlags, opts)
+	if err != nil {
+		return nil, namespace, err
+	}
+
+	var candidates []PodCandidate
+	for i := range pods {
+		pod := &pods[i]
+		// Completed pods, e.g. of finished jobs, need no triage
+		if pod.Status.Phase == corev1.PodSucceeded || isPodTrulyHealthy(pod, opts.Config) {
+			continue
+		}
+		candidates = append(candidates, podCandidate(pod, opts.Config))
+	}
+
+	sortCandidates(candidates)
+	return candidates, namespace, nil
+}
+
+// listPods lists the pods in the namespace resolved from opts and configFlags,
+// bounded by opts.Timeout
+func listPods(ctx context.Context, configFlags *genericclioptions.ConfigFlags, opts *TriageOptions) ([]corev1.Pod, string, error) {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	clientset, err := opts.client(configFlags, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	namespace := opts.namespace(configFlags)
+
+	var pods *corev1.PodList
+	err = runWithContext(ctx, func() error {
+		list, err := clientset.CoreV1().Pods(namespace).List(metav1.ListOptions{})
+		pods = list
+		return err
+	})
+	if err != nil {
+		return nil, namespace, fmt.Errorf("failed to list pods in namespace %s: %w", namespace, err)
+	}
+	return pods.Items, namespace, nil
+}
+
+// sortCandidates orders candidates by most recent failure first, then by name
+func sortCandidates(candidates []PodCandidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if !candidates[i].LastFailure.Equal(candidates[j].LastFailure) {
+			return candidates[i].LastFailure.After(candidates[j].LastFailure)
+		}
+		return candidates[i].Name < candidates[j].Name
+	})
+}
+
+// CandidateLabels aligns pod names and their summaries into columns
+func CandidateLabels(candidates []PodCandidate) []string {
+	width := 0
+	for _, candidate := range candidates {
+		if len(candidate.Name) > width {
+			width = len(candidate.Name)
+		}
+	}
+
+	labels := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		labels[i] = fmt.Sprintf("%-*s  %s", width, candidate.Name, candidate.Summary())
+	}
+	return labels
+}
+
+// podCandidate summarises why a pod is unhealthy, or "Healthy", and when it last failed
+func podCandidate(pod *corev1.Pod, config *Config) PodCandidate {
+	candidate := PodCandidate{Name: pod.Name}
+
+	failed, _ := identifyFailedContainers(pod, false, config)
+	for _, container := range failed {
+		candidate.Restarts += container.RestartCount
+		if candidate.Reason == "" {
+			candidate.Reason = container.Reason
+		}
+	}
+	if candidate.Reason == "" {
+		switch {
+		case len(failed) > 0:
+			candidate.Reason = "Restarting"
+		case isPodTrulyHealthy(pod, config):
+			candidate.Reason = "Healthy"
+		case pod.Status.Phase == corev1.PodRunning:
+			candidate.Reason = "NotReady"
+		default:
+			candidate.Reason = string(pod.Status.Phase)
+		}
+	}
+
+	latest := func(t metav1.Time) {
+		if t.Time.After(candidate.LastFailure) {
+			candidate.LastFailure = t.Time
+		}
+	}
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.State.Terminated != nil {
+			latest(cs.State.Terminated.FinishedAt)
+		}
+		if cs.LastTerminationState.Terminated != nil {
+			latest(cs.LastTerminationState.Terminated.FinishedAt)
+		}
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady && condition.Status != corev1.ConditionTrue {
+			latest(condition.LastTransitionTime)
+		}
+	}
+	return candidate
+}
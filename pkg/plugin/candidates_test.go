@@ -0,0 +1,62 @@
+package plugin
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+)
+
+// TestUnhealthyPods tests candidate selection and most-recent-failure ordering
+func TestUnhealthyPods(t *testing.T) {
+	now := time.Now()
+	failedAt := func(status corev1.ContainerStatus, ago time.Duration) corev1.ContainerStatus {
+		status.LastTerminationState.Terminated = &corev1.ContainerStateTerminated{
+			Reason:     "Error",
+			FinishedAt: metav1.NewTime(now.Add(-ago)),
+		}
+		return status
+	}
+
+	oldCrash := runningPod("old-crash", corev1.PodRunning, false, failedAt(waiting("app", "CrashLoopBackOff", 9), time.Hour))
+	newCrash := runningPod("new-crash", corev1.PodRunning, false, failedAt(waiting("app", "CrashLoopBackOff", 2), time.Minute))
+	liveness := runningPod("liveness", corev1.PodRunning, true, failedAt(running("app", 3, true), 10*time.Minute))
+	pending := runningPod("pending", corev1.PodPending, false)
+	healthy := runningPod("healthy", corev1.PodRunning, true, running("app", 0, true))
+	completed := runningPod("completed", corev1.PodSucceeded, false)
+
+	clientset := newFakeClientset(nil, oldCrash, newCrash, liveness, pending, healthy, completed)
+	opts := &TriageOptions{
+		Namespace: testNamespace,
+		NewClient: func(*genericclioptions.ConfigFlags) (kubernetes.Interface, error) {
+			return clientset, nil
+		},
+	}
+
+	candidates, namespace, err := UnhealthyPods(context.Background(), genericclioptions.NewConfigFlags(false), opts)
+	if err != nil {
+		t.Fatalf("UnhealthyPods() error = %v", err)
+	}
+	if namespace != testNamespace {
+		t.Errorf("UnhealthyPods() namespace = %q, want %q", namespace, testNamespace)
+	}
+
+	var summaries [][]interface{}
+	for _, candidate := range candidates {
+		summaries = append(summaries, []interface{}{candidate.Name, candidate.Reason, candidate.Restarts})
+	}
+	expected := [][]interface{}{
+		{"new-crash", "CrashLoopBackOff", int32(2)},
+		{"liveness", "Restarting", int32(3)},
+		{"old-crash", "CrashLoopBackOff", int32(9)},
+		{"pending", "Pending", int32(0)},
+	}
+	if !reflect.DeepEqual(summaries, expected) {
+		t.Errorf("UnhealthyPods() = %v, want %v", summaries, expected)
+	}
+}
@@ -0,0 +1,162 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+)
+
+// compactPod writes a pod in the compact layout: a status line per triaged
+// container, then the first events, the crash, the last log lines and the
+// first next step, each line cut to the terminal width
+func (t *textWriter) compactPod(pod PodReport) {
+	if pod.Status == StatusError {
+		t.line(styleError, t.fit(fmt.Sprintf("❌ Couldn't triage pod '%s': %s", pod.Name, pod.Message)))
+		return
+	}
+	if pod.Skipped {
+		t.line(styleInfo, fmt.Sprintf("✅ Pod '%s' is healthy (Ready %s, 0 restarts); use --force to inspect anyway.", pod.Name, pod.Ready))
+		return
+	}
+
+	for i, container := range pod.triagedContainers() {
+		if i > 0 {
+			t.line(styleInfo, "")
+		}
+		if container.Failed {
+			t.line(styleError, t.fit(fmt.Sprintf("🚨 FAILED '%s': %s | Phase: %s | Restarts: %d | Ready: %s",
+				container.Name, container.Status(), pod.Phase, container.RestartCount, pod.Ready)))
+		} else {
+			t.line(styleInfo, t.fit(fmt.Sprintf("🔍 '%s': %s | Phase: %s | Restarts: %d | Ready: %s",
+				container.Name, container.Status(), pod.Phase, container.RestartCount, pod.Ready)))
+		}
+
+		if i == 0 {
+			t.compactEvents(pod)
+		}
+		if container.Crash != nil {
+			t.line(styleError, fmt.Sprintf("💥 CRASH (%s)", container.Crash.Language))
+			for _, line := range compactCrash(container) {
+				t.line(lineStyle(line), t.fit(line.Text))
+			}
+		}
+		// The current logs mostly repeat the previous ones, so they are only
+		// shown when there are no previous lines
+		t.compactLogs("🔥 PREVIOUS LOGS", "No previous logs", container.Previous)
+		if len(container.Previous.Lines) == 0 {
+			t.compactLogs("🔄 CURRENT LOGS", "No current logs", container.Current)
+		}
+	}
+
+	if others := pod.otherContainers(); len(others) > 0 {
+		t.line(styleInfo, t.fit(fmt.Sprintf("ℹ️  %d other container(s) running normally: [%s]", len(others), strings.Join(others, ", "))))
+	}
+
+	if len(pod.NextSteps) > 0 {
+		step := pod.NextSteps[0]
+		t.line(styleInfo, t.fit("🛠️  NEXT STEP: "+step.Title))
+		// Commands are left whole, as a cut one would fail when pasted
+		for _, command := range step.Commands {
+			t.line(styleInfo, "      "+command)
+		}
+	}
+
+	if pod.Redact {
+		if pod.Redacted > 0 {
+			t.line(styleInfo, t.fit(fmt.Sprintf("🔒 Redacted %d item(s): %s", pod.Redacted, pod.RedactedSummary)))
+		} else {
+			t.line(styleInfo, "🔒 Redaction on: nothing sensitive found")
+		}
+	}
+}
+
+// compactEvents writes the most recent events, or why there are none
+func (t *textWriter) compactEvents(pod PodReport) {
+	switch {
+	case pod.EventsForbidden:
+		t.line(styleWarn, t.fit(fmt.Sprintf("⚠️  EVENTS - skipped (%s)", pod.EventsError)))
+	case len(pod.Events) == 0:
+		if pod.EventsError != "" {
+			t.line(styleInfo, t.fit(fmt.Sprintf("⚠️  EVENTS - incomplete (%s)", pod.EventsError)))
+		}
+	default:
+		events := pod.Events
+		if len(events) > compactEvents {
+			events = events[:compactEvents]
+		}
+		t.line(styleInfo, fmt.Sprintf("⚠️  EVENTS (%d of %d)", len(events), len(pod.Events)))
+		for i, columns := range eventColumns(events) {
+			t.line(eventStyle(events[i]), t.fit(columns+events[i].Message))
+		}
+	}
+}
+
+// compactCrash picks the crash lines to show: the first one, which holds the
+// error, and the lines around the first application frame
+func compactCrash(container ContainerReport) []LogLine {
+	lines := container.CrashLines
+	if len(lines) <= compactCrashLines {
+		return lines
+	}
+
+	start, _ := container.Crash.window()
+	app := container.Crash.AppFrame - start
+	if app < 0 || app >= len(lines) {
+		return lines[:compactCrashLines]
+	}
+	from := app - 1
+	if from < 1 {
+		from = 1
+	}
+	to := from + compactCrashLines - 1
+	if to > len(lines) {
+		to = len(lines)
+		from = to - (compactCrashLines - 1)
+	}
+	return append([]LogLine{lines[0]}, lines[from:to]...)
+}
+
+// compactLogs writes the last lines of a log section under a one-line title,
+// or a line saying why there are none
+func (t *textWriter) compactLogs(title, errorText string, section LogSection) {
+	switch {
+	case section.Empty():
+		return
+	case section.Forbidden:
+		t.line(styleWarn, t.fit(fmt.Sprintf("%s - skipped (%s)", title, section.Error)))
+		return
+	case section.Error != "" && len(section.Lines) == 0:
+		if section.Incomplete {
+			t.line(styleInfo, t.fit(fmt.Sprintf("%s - incomplete (%s)", title, section.Error)))
+		} else {
+			t.line(styleInfo, t.fit(fmt.Sprintf("%s - %s (%s)", title, errorText, section.Error)))
+		}
+		return
+	}
+
+	lines := displayLines(section.Lines)
+	if len(lines) == 0 {
+		note := "empty"
+		if section.Grep != "" {
+			note = fmt.Sprintf("no lines match %q", section.Grep)
+		} else if section.Hidden > 0 {
+			note = fmt.Sprintf("%d line(s) below %s hidden", section.Hidden, section.MinLevel)
+		}
+		t.line(styleInfo, t.fit(title+" - "+note))
+		return
+	}
+
+	if len(lines) > compactLogLines {
+		lines = lines[len(lines)-compactLogLines:]
+	}
+	heading := fmt.Sprintf("%s - last %d line(s)", title, len(lines))
+	if section.Grep != "" {
+		heading = fmt.Sprintf("%s - last %d line(s) matching %q", title, len(lines), section.Grep)
+	}
+	if section.Incomplete {
+		heading += ", fetch cut short"
+	}
+	t.line(styleInfo, t.fit(heading))
+	for _, line := range lines {
+		t.line(lineStyle(line), t.fit("  "+line.Text))
+	}
+}
@@ -0,0 +1,91 @@
+package plugin
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+// CompletePodNames returns the pods starting with prefix as shell completions,
+// "name\tsummary", with unhealthy pods first, most recent failure first
+func CompletePodNames(ctx context.Context, configFlags *genericclioptions.ConfigFlags, opts *TriageOptions, prefix string) ([]string, error) {
+	pods, _, err := listPods(ctx, configFlags, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var unhealthy, healthy []PodCandidate
+	for i := range pods {
+		pod := &pods[i]
+		if !strings.HasPrefix(pod.Name, prefix) {
+			continue
+		}
+		if pod.Status.Phase == corev1.PodSucceeded || isPodTrulyHealthy(pod, opts.Config) {
+			healthy = append(healthy, PodCandidate{Name: pod.Name, Reason: "Healthy"})
+			continue
+		}
+		unhealthy = append(unhealthy, podCandidate(pod, opts.Config))
+	}
+	sortCandidates(unhealthy)
+	sortCandidates(healthy)
+
+	var completions []string
+	for _, candidate := range append(unhealthy, healthy...) {
+		completions = append(completions, candidate.Name+"\t"+candidate.Summary())
+	}
+	return completions, nil
+}
+
+// CompleteNamespaces returns the namespaces starting with prefix
+func CompleteNamespaces(ctx context.Context, configFlags *genericclioptions.ConfigFlags, opts *TriageOptions, prefix string) ([]string, error) {
+	clientset, err := opts.client(configFlags, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	var namespaces *corev1.NamespaceList
+	err = runWithContext(ctx, func() error {
+		list, err := clientset.CoreV1().Namespaces().List(metav1.ListOptions{})
+		namespaces = list
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, namespace := range namespaces.Items {
+		if strings.HasPrefix(namespace.Name, prefix) {
+			names = append(names, namespace.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// CompleteContexts returns the kubeconfig contexts starting with prefix
+func CompleteContexts(configFlags *genericclioptions.ConfigFlags, prefix string) ([]string, error) {
+	config, err := configFlags.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for name := range config.Contexts {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
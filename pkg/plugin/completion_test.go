@@ -0,0 +1,38 @@
+package plugin
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+)
+
+// TestCompletePodNames tests prefix filtering and unhealthy-first ordering
+func TestCompletePodNames(t *testing.T) {
+	clientset := newFakeClientset(nil,
+		runningPod("web-healthy", corev1.PodRunning, true, running("app", 0, true)),
+		runningPod("web-crash", corev1.PodRunning, false, waiting("app", "CrashLoopBackOff", 4)),
+		runningPod("api", corev1.PodPending, false),
+	)
+	opts := &TriageOptions{
+		Namespace: testNamespace,
+		NewClient: func(*genericclioptions.ConfigFlags) (kubernetes.Interface, error) {
+			return clientset, nil
+		},
+	}
+
+	completions, err := CompletePodNames(context.Background(), genericclioptions.NewConfigFlags(false), opts, "web")
+	if err != nil {
+		t.Fatalf("CompletePodNames() error = %v", err)
+	}
+	expected := []string{
+		"web-crash\tCrashLoopBackOff, 4 restarts",
+		"web-healthy\tHealthy, 0 restarts",
+	}
+	if !reflect.DeepEqual(completions, expected) {
+		t.Errorf("CompletePodNames() = %q, want %q", completions, expected)
+	}
+}
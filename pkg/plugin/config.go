@@ -0,0 +1,197 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/Lc-Lin/kubectl-triage/pkg/logger"
+	"github.com/spf13/viper"
+)
+
+const (
+	// UserConfigFile is read from the home directory, next to the kubeconfig
+	UserConfigFile = ".kube/triage.yaml"
+
+	// RepoConfigFile is looked up from the working directory upwards, so a team
+	// can commit shared settings to its repository
+	RepoConfigFile = ".triage.yaml"
+)
+
+// Config holds the team and user settings loaded from triage.yaml files
+type Config struct {
+	// Highlight rules are checked before the built-in keywords
+	Highlight []HighlightRule `mapstructure:"highlight"`
+	// FailureReasons are added to the built-in container failure reasons
+	FailureReasons FailureReasons `mapstructure:"failureReasons"`
+	// IgnoreContainers are never triaged, e.g. mesh sidecars that restart harmlessly
+	IgnoreContainers []string `mapstructure:"ignoreContainers"`
+	// LogKeys are extra field names for JSON and logfmt logs
+	LogKeys LogKeys `mapstructure:"logKeys"`
+	// Redact rules mask extra secrets on top of the built-in detectors
+	Redact []RedactRule `mapstructure:"redact"`
+}
+
+// HighlightRule colors plain-text log lines matching a regular expression
+type HighlightRule struct {
+	Pattern string `mapstructure:"pattern"`
+	// Color is one of red, yellow, green, blue, magenta, cyan or white;
+	// it defaults from the severity
+	Color string `mapstructure:"color"`
+	// Severity is the log level given to matching lines, used by --min-level
+	Severity string `mapstructure:"severity"`
+
+	regexp *regexp.Regexp
+}
+
+// FailureReasons are container state reasons that mark a container as failed
+type FailureReasons struct {
+	Waiting    []string `mapstructure:"waiting"`
+	Terminated []string `mapstructure:"terminated"`
+}
+
+// severityColors are the rule colors used when a rule only sets a severity
+var severityColors = map[string]string{
+	LevelDebug: "white",
+	LevelInfo:  "cyan",
+	LevelWarn:  "yellow",
+	LevelError: "red",
+	LevelFatal: "red",
+}
+
+// ConfigFiles returns the config files that exist, in load order: the user
+// file, the nearest repository file, then the explicitly given file, which
+// must exist
+func ConfigFiles(explicit string) ([]string, error) {
+	var files []string
+
+	if home, err := os.UserHomeDir(); err == nil {
+		if path := filepath.Join(home, UserConfigFile); fileExists(path) {
+			files = append(files, path)
+		}
+	}
+
+	if dir, err := os.Getwd(); err == nil {
+		for {
+			if path := filepath.Join(dir, RepoConfigFile); fileExists(path) {
+				files = append(files, path)
+				break
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+	}
+
+	if explicit != "" {
+		if !fileExists(explicit) {
+			return nil, fmt.Errorf("config file %s not found", explicit)
+		}
+		files = append(files, explicit)
+	}
+
+	return files, nil
+}
+
+// LoadConfig reads the given files and merges them into one Config
+// List settings from later files are added to those of earlier ones
+func LoadConfig(files []string) (*Config, error) {
+	config := &Config{}
+	for _, file := range files {
+		v := viper.New()
+		v.SetConfigFile(file)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("failed to read config file %s: %w", file, err)
+		}
+
+		var fileConfig Config
+		if err := v.Unmarshal(&fileConfig); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", file, err)
+		}
+		if err := fileConfig.compile(); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", file, err)
+		}
+		config.merge(&fileConfig)
+	}
+
+	return config, nil
+}
+
+// compile checks the rules and precompiles their patterns
+func (c *Config) compile() error {
+	for i := range c.Highlight {
+		rule := &c.Highlight[i]
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return fmt.Errorf("highlight pattern %q: %v", rule.Pattern, err)
+		}
+		rule.regexp = re
+
+		if rule.Severity != "" {
+			level := normalizeLevel(rule.Severity)
+			if level == "" {
+				return fmt.Errorf("highlight pattern %q: unknown severity %q", rule.Pattern, rule.Severity)
+			}
+			rule.Severity = level
+		}
+		if rule.Color == "" {
+			rule.Color = severityColors[rule.Severity]
+		}
+		if rule.Color != "" && !logger.IsColor(rule.Color) {
+			return fmt.Errorf("highlight pattern %q: unknown color %q", rule.Pattern, rule.Color)
+		}
+	}
+
+	for i := range c.Redact {
+		if err := c.Redact[i].compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Config) merge(other *Config) {
+	c.Highlight = append(c.Highlight, other.Highlight...)
+	c.FailureReasons.Waiting = append(c.FailureReasons.Waiting, other.FailureReasons.Waiting...)
+	c.FailureReasons.Terminated = append(c.FailureReasons.Terminated, other.FailureReasons.Terminated...)
+	c.IgnoreContainers = append(c.IgnoreContainers, other.IgnoreContainers...)
+	c.LogKeys.Level = append(c.LogKeys.Level, other.LogKeys.Level...)
+	c.LogKeys.Message = append(c.LogKeys.Message, other.LogKeys.Message...)
+	c.LogKeys.Error = append(c.LogKeys.Error, other.LogKeys.Error...)
+	c.LogKeys.Time = append(c.LogKeys.Time, other.LogKeys.Time...)
+	c.Redact = append(c.Redact, other.Redact...)
+}
+
+// matchHighlight returns the first rule matching line, if any
+func (c *Config) matchHighlight(line string) *HighlightRule {
+	if c == nil {
+		return nil
+	}
+	for i := range c.Highlight {
+		if c.Highlight[i].regexp != nil && c.Highlight[i].regexp.MatchString(line) {
+			return &c.Highlight[i]
+		}
+	}
+	return nil
+}
+
+// isIgnored reports whether a container is listed in ignoreContainers
+func (c *Config) isIgnored(container string) bool {
+	if c == nil {
+		return false
+	}
+	for _, name := range c.IgnoreContainers {
+		if name == container {
+			return true
+		}
+	}
+	return false
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,165 @@
+package plugin
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+)
+
+// writeConfig writes a config file into a test directory
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+// TestLoadConfig tests that user and team files are merged
+func TestLoadConfig(t *testing.T) {
+	user := writeConfig(t, "triage.yaml", `
+highlight:
+  - pattern: 'connection refused'
+    severity: error
+ignoreContainers: [istio-proxy]
+`)
+	team := writeConfig(t, ".triage.yaml", `
+highlight:
+  - pattern: 'GET /healthz'
+    severity: debug
+failureReasons:
+  waiting: [CreateContainerConfigError]
+ignoreContainers: [linkerd-proxy]
+logKeys:
+  level: [sev]
+`)
+
+	config, err := LoadConfig([]string{user, team})
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	if len(config.Highlight) != 2 || len(config.IgnoreContainers) != 2 {
+		t.Errorf("LoadConfig() = %+v, want lists from both files", config)
+	}
+	if rule := config.matchHighlight("dial tcp: connection refused"); rule == nil || rule.Color != "red" || rule.Severity != LevelError {
+		t.Errorf("matchHighlight() = %+v, want red error rule", rule)
+	}
+	if rule := config.matchHighlight("GET /healthz 200"); rule == nil || rule.Severity != LevelDebug {
+		t.Errorf("matchHighlight() = %+v, want debug rule", rule)
+	}
+	if config.FailureReasons.Waiting[0] != "CreateContainerConfigError" || config.LogKeys.Level[0] != "sev" {
+		t.Errorf("LoadConfig() = %+v, want failure reasons and log keys", config)
+	}
+}
+
+// TestLoadConfigInvalid tests that bad rules are reported with the file name
+func TestLoadConfigInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:     "Bad regex",
+			content:  "highlight:\n  - pattern: '(unclosed'\n",
+			expected: "highlight pattern",
+		},
+		{
+			name:     "Unknown color",
+			content:  "highlight:\n  - pattern: x\n    color: plaid\n",
+			expected: `unknown color "plaid"`,
+		},
+		{
+			name:     "Unknown severity",
+			content:  "highlight:\n  - pattern: x\n    severity: loud\n",
+			expected: `unknown severity "loud"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, "triage.yaml", tt.content)
+			_, err := LoadConfig([]string{path})
+			if err == nil || !strings.Contains(err.Error(), tt.expected) || !strings.Contains(err.Error(), path) {
+				t.Errorf("LoadConfig() error = %v, want %q", err, tt.expected)
+			}
+		})
+	}
+}
+
+// TestIdentifyFailedContainersWithConfig tests extra failure reasons and ignored containers
+func TestIdentifyFailedContainersWithConfig(t *testing.T) {
+	pod := &corev1.Pod{
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				waiting("app", "CreateContainerConfigError", 0),
+				running("istio-proxy", 3, true),
+			},
+		},
+	}
+	config := &Config{
+		FailureReasons:   FailureReasons{Waiting: []string{"CreateContainerConfigError"}},
+		IgnoreContainers: []string{"istio-proxy"},
+	}
+
+	failed, healthy := identifyFailedContainers(pod, false, config)
+	if len(failed) != 1 || failed[0].Name != "app" {
+		t.Errorf("identifyFailedContainers() failed = %+v, want [app]", failed)
+	}
+	if len(healthy) != 1 || !healthy[0].Ignored {
+		t.Errorf("identifyFailedContainers() healthy = %+v, want ignored istio-proxy", healthy)
+	}
+
+	// Without the config the same pod only flags the restarting sidecar
+	failed, _ = identifyFailedContainers(pod, false, nil)
+	if len(failed) != 1 || failed[0].Name != "istio-proxy" {
+		t.Errorf("identifyFailedContainers() without config = %+v, want [istio-proxy]", failed)
+	}
+}
+
+// TestRunPluginIgnoredSidecar checks that a pod whose only restarts are in an
+// ignored container is reported healthy and exits 0, also when --force
+// triages it anyway
+func TestRunPluginIgnoredSidecar(t *testing.T) {
+	for _, force := range []bool{false, true} {
+		pod := runningPod("web", corev1.PodRunning, true, running("app", 0, true), running("istio-proxy", 2, true))
+		clientset := newFakeClientset(nil, pod)
+		opts := &TriageOptions{
+			PodName:   pod.Name,
+			Namespace: testNamespace,
+			Lines:     50,
+			Force:     force,
+			NoColor:   true,
+			Config:    &Config{IgnoreContainers: []string{"istio-proxy"}},
+			NewClient: func(*genericclioptions.ConfigFlags) (kubernetes.Interface, error) {
+				return clientset, nil
+			},
+		}
+
+		output, runErr := runPlugin(opts)
+		if code := ExitCode(runErr); code != ExitHealthy {
+			t.Errorf("RunPlugin(force=%v) error = %v, exit code %d; want %d", force, runErr, code, ExitHealthy)
+		}
+		if !force && !strings.Contains(output, "✅ Pod 'web' is healthy") {
+			t.Errorf("output doesn't report the pod healthy:\n%s", output)
+		}
+	}
+}
+
+// TestExampleConfig keeps examples/triage.yaml loadable
+func TestExampleConfig(t *testing.T) {
+	config, err := LoadConfig([]string{filepath.Join("..", "..", "examples", "triage.yaml")})
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if len(config.Highlight) == 0 || len(config.IgnoreContainers) == 0 {
+		t.Errorf("LoadConfig() = %+v, want example rules", config)
+	}
+}
@@ -0,0 +1,89 @@
+package plugin
+
+import (
+	"context"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// eventPageSize is how many events each page of the namespace list holds
+const eventPageSize = 500
+
+// eventIndex holds the events of a namespace that aren't Normal by the object
+// they are about, so the pods of a run share one paginated list instead of
+// one list each. It is filled on first use, so a run where every pod is
+// skipped as healthy doesn't list events at all
+type eventIndex struct {
+	once     sync.Once
+	byObject map[objectKey][]corev1.Event
+	err      error
+}
+
+// objectKey identifies the object an event is about within its namespace
+type objectKey struct {
+	kind string
+	name string
+}
+
+// podEvents returns the relevant events of a pod, from the shared index when
+// the run has one
+func (r *triageRun) podEvents(ctx context.Context, pod *corev1.Pod) ([]EventInfo, error) {
+	if r.events == nil {
+		var events []EventInfo
+		err := r.pool.do(ctx, func() error {
+			var err error
+			events, err = getRelevantEvents(ctx, r.clientset, pod.Namespace, pod.Name)
+			return err
+		})
+		return events, err
+	}
+
+	r.events.once.Do(func() {
+		r.events.byObject, r.events.err = listEvents(ctx, r.clientset, r.pool, pod.Namespace)
+	})
+	if r.events.err != nil {
+		return nil, r.events.err
+	}
+	// Events can lack a UID; those that have one may be about an earlier pod
+	// of the same name, e.g. a StatefulSet pod that was recreated
+	var items []corev1.Event
+	for _, event := range r.events.byObject[objectKey{kind: "Pod", name: pod.Name}] {
+		if event.InvolvedObject.UID == "" || event.InvolvedObject.UID == pod.UID {
+			items = append(items, event)
+		}
+	}
+	return relevantEvents(items), nil
+}
+
+// listEvents lists the events of a namespace that aren't Normal page by page,
+// indexed by their involved object. The field selector leaves out the bulk of
+// the events while keeping every type relevantEvents shows, so a run over
+// several pods shows the same events as one pod at a time
+func listEvents(ctx context.Context, clientset kubernetes.Interface, pool *workerPool, namespace string) (map[objectKey][]corev1.Event, error) {
+	byObject := map[objectKey][]corev1.Event{}
+	opts := metav1.ListOptions{FieldSelector: "type!=" + corev1.EventTypeNormal, Limit: eventPageSize}
+	for {
+		var list *corev1.EventList
+		err := pool.do(ctx, func() error {
+			return runWithContext(ctx, func() error {
+				var err error
+				list, err = clientset.CoreV1().Events(namespace).List(opts)
+				return err
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range list.Items {
+			key := objectKey{kind: event.InvolvedObject.Kind, name: event.InvolvedObject.Name}
+			byObject[key] = append(byObject[key], event)
+		}
+		if list.Continue == "" {
+			return byObject, nil
+		}
+		opts.Continue = list.Continue
+	}
+}
@@ -0,0 +1,91 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// TestRunPluginEventsIndex checks that several pods share one paginated list
+// of the namespace's events, each pod getting its own events, including those
+// without a UID but not those of an earlier pod of the same name
+func TestRunPluginEventsIndex(t *testing.T) {
+	web1 := runningPod("web-1", corev1.PodRunning, false, waiting("app", "CrashLoopBackOff", 4))
+	web2 := runningPod("web-2", corev1.PodRunning, false, waiting("app", "ImagePullBackOff", 0))
+	web3 := runningPod("web-3", corev1.PodRunning, false, waiting("app", "CrashLoopBackOff", 2))
+	noUID := podEvent(web3, "Error", "Unhealthy", "Readiness probe failed for web-3", 0)
+	noUID.InvolvedObject.UID = ""
+	earlier := podEvent(web1, corev1.EventTypeWarning, "FailedMount", "Old mount failure for web-1", 0)
+	earlier.InvolvedObject.UID = "uid-earlier-web-1"
+	pages := []*corev1.EventList{
+		{Items: []corev1.Event{
+			podEvent(web1, corev1.EventTypeWarning, "BackOff", "Back-off restarting web-1", 0),
+			podEvent(web2, corev1.EventTypeWarning, "Failed", "Failed to pull image for web-2", 0),
+		}},
+		{Items: []corev1.Event{
+			podEvent(web3, corev1.EventTypeWarning, "BackOff", "Back-off restarting web-3", 0),
+			noUID,
+			earlier,
+		}},
+	}
+	pages[0].Continue = "page-2"
+	clientset := newFakeClientset(map[string]containerLogs{
+		"app": {Previous: "ERROR: database connection failed\n"},
+	}, web1, web2, web3)
+
+	var selectors []string
+	clientset.PrependReactor("list", "events", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		selectors = append(selectors, action.(k8stesting.ListAction).GetListRestrictions().Fields.String())
+		return true, pages[len(selectors)-1], nil
+	})
+
+	opts := &TriageOptions{
+		PodName:   web1.Name,
+		PodNames:  []string{web2.Name, web3.Name},
+		Namespace: testNamespace,
+		Lines:     50,
+		NoColor:   true,
+		NewClient: func(*genericclioptions.ConfigFlags) (kubernetes.Interface, error) {
+			return clientset, nil
+		},
+	}
+
+	output, _ := runPlugin(opts)
+	if len(selectors) != 2 {
+		t.Fatalf("events listed %d time(s), want 2 pages", len(selectors))
+	}
+	for _, selector := range selectors {
+		if selector != "type!=Normal" {
+			t.Errorf("events listed with field selector %q, want type!=Normal", selector)
+		}
+	}
+
+	// Each pod's section holds its own event and none of the others'
+	sections := strings.Split(output, "📦 POD ")
+	if len(sections) != 4 {
+		t.Fatalf("output has %d pod section(s), want 3:\n%s", len(sections)-1, output)
+	}
+	for i, name := range []string{"web-1", "web-2", "web-3"} {
+		section := sections[i+1]
+		if !strings.HasPrefix(section, testNamespace+"/"+name) {
+			t.Errorf("section %d is not for %s:\n%s", i, name, section)
+		}
+		for _, other := range []string{"web-1", "web-2", "web-3"} {
+			mentioned := strings.Contains(section, "restarting "+other) || strings.Contains(section, "image for "+other)
+			if mentioned != (other == name) {
+				t.Errorf("section for %s mentions %s's event: %v\n%s", name, other, mentioned, section)
+			}
+		}
+	}
+	if !strings.Contains(sections[3], "Readiness probe failed for web-3") {
+		t.Errorf("section for web-3 lacks its event without a UID:\n%s", sections[3])
+	}
+	if strings.Contains(output, "Old mount failure") {
+		t.Errorf("output has the event of an earlier pod named web-1:\n%s", output)
+	}
+}
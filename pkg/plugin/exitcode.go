@@ -0,0 +1,151 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Exit codes of kubectl-triage, so scripts and pipelines can branch on the outcome
+const (
+	// ExitHealthy means the pod is healthy
+	ExitHealthy = 0
+	// ExitError means the command itself failed, e.g. a bad flag or config file
+	ExitError = 1
+	// ExitFailing means a container is crash-looping, failing to start or terminated with an error
+	ExitFailing = 2
+	// ExitDegraded means the pod runs but has restarted or isn't ready
+	ExitDegraded = 3
+	// ExitPending means the pod hasn't started its containers, e.g. it isn't scheduled yet
+	ExitPending = 4
+	// ExitNotFound means no pod matched the given name or selector
+	ExitNotFound = 5
+	// ExitAPIError means the API server refused a request (e.g. RBAC) or couldn't be reached
+	ExitAPIError = 6
+	// ExitPartial means the timeout or Ctrl-C cut collection short
+	ExitPartial = 7
+)
+
+// Values for TriageOptions.FailOn
+const (
+	// FailOnWarning exits non-zero for degraded and pending pods too
+	FailOnWarning = "warning"
+	// FailOnError exits non-zero only for failing pods
+	FailOnError = "error"
+)
+
+// OutcomeError reports a triage outcome that maps to a non-zero exit code
+type OutcomeError struct {
+	Code    int
+	Message string
+}
+
+func (e *OutcomeError) Error() string {
+	return e.Message
+}
+
+// Reported is true for outcomes the triage output already explains, so the
+// error needs no printing of its own
+func (e *OutcomeError) Reported() bool {
+	return e.Code == ExitFailing || e.Code == ExitDegraded || e.Code == ExitPending
+}
+
+// ExitCode maps an error returned by RunPlugin to the documented exit code
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitHealthy
+	}
+
+	var outcome *OutcomeError
+	if errors.As(err, &outcome) {
+		return outcome.Code
+	}
+
+	var notFound *PodNotFoundError
+	if errors.As(err, &notFound) {
+		return ExitNotFound
+	}
+
+	var denied *ForbiddenError
+	if errors.As(err, &denied) {
+		return ExitAPIError
+	}
+
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		if status.Status().Reason == metav1.StatusReasonNotFound {
+			return ExitNotFound
+		}
+		return ExitAPIError
+	}
+
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) || isContextError(err) {
+		return ExitAPIError
+	}
+
+	return ExitError
+}
+
+// podOutcome classifies a triaged pod; containers the config ignores don't
+// count, and config can be nil
+func podOutcome(pod *corev1.Pod, failedContainers []ContainerInfo, config *Config) *OutcomeError {
+	degraded := !isPodTrulyHealthy(pod, config)
+	for _, container := range failedContainers {
+		if !container.Failed || container.Ignored {
+			continue
+		}
+		if container.State != "Running" {
+			return &OutcomeError{
+				Code:    ExitFailing,
+				Message: fmt.Sprintf("pod %s is failing: container %s is %s (%s)", pod.Name, container.Name, container.State, container.Reason),
+			}
+		}
+		degraded = true
+	}
+
+	switch {
+	case pod.Status.Phase == corev1.PodFailed:
+		return &OutcomeError{
+			Code:    ExitFailing,
+			Message: fmt.Sprintf("pod %s has failed: %s", pod.Name, pod.Status.Reason),
+		}
+	case pod.Status.Phase == corev1.PodSucceeded:
+		return nil
+	case pod.Status.Phase == corev1.PodPending:
+		return &OutcomeError{Code: ExitPending, Message: fmt.Sprintf("pod %s is pending", pod.Name)}
+	case degraded:
+		return &OutcomeError{Code: ExitDegraded, Message: fmt.Sprintf("pod %s is degraded: restarted or not ready", pod.Name)}
+	}
+	return nil
+}
+
+// failsOn reports whether an outcome should fail the command under --fail-on
+func failsOn(outcome *OutcomeError, failOn string) bool {
+	if outcome == nil {
+		return false
+	}
+	if failOn == FailOnError {
+		return outcome.Code == ExitFailing
+	}
+	return true
+}
+
+// outcomeRank orders outcomes by severity for runs over several pods
+var outcomeRank = map[int]int{
+	ExitDegraded: 1,
+	ExitPending:  2,
+	ExitFailing:  3,
+}
+
+// worseOutcome returns the more severe of two outcomes, preferring the first on a tie
+func worseOutcome(a, b *OutcomeError) *OutcomeError {
+	if a == nil || (b != nil && outcomeRank[b.Code] > outcomeRank[a.Code]) {
+		return b
+	}
+	return a
+}
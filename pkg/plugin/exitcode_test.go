@@ -0,0 +1,106 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// TestExitCode tests the mapping of RunPlugin errors to exit codes
+func TestExitCode(t *testing.T) {
+	pods := schema.GroupResource{Resource: "pods"}
+
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"Healthy", nil, ExitHealthy},
+		{"Failing", &OutcomeError{Code: ExitFailing}, ExitFailing},
+		{"Partial", fmt.Errorf("triage incomplete: %w", &OutcomeError{Code: ExitPartial}), ExitPartial},
+		{"Not found", fmt.Errorf("failed to get pod: %w", apierrors.NewNotFound(pods, "web")), ExitNotFound},
+		{"Forbidden", fmt.Errorf("failed to get events: %w", apierrors.NewForbidden(pods, "web", errors.New("no RBAC"))), ExitAPIError},
+		{"Forbidden by access review", fmt.Errorf("failed to get pod web: %w", &ForbiddenError{Permission: getPodsPermission, Namespace: "prod"}), ExitAPIError},
+		{"Unreachable", fmt.Errorf("failed to get pod: %w", &url.Error{Op: "Get", URL: "https://10.0.0.1", Err: errors.New("connection refused")}), ExitAPIError},
+		{"Timed out", fmt.Errorf("failed to get pod: %w", context.DeadlineExceeded), ExitAPIError},
+		{"Bad config", errors.New("invalid config file"), ExitError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := ExitCode(tt.err); code != tt.expected {
+				t.Errorf("ExitCode(%v) = %d, want %d", tt.err, code, tt.expected)
+			}
+		})
+	}
+}
+
+// TestPodOutcome tests pod classification and the --fail-on threshold
+func TestPodOutcome(t *testing.T) {
+	tests := []struct {
+		name           string
+		pod            *corev1.Pod
+		config         *Config
+		expectedCode   int
+		failsOnError   bool
+		failsOnWarning bool
+	}{
+		{
+			name:           "Crash loop",
+			pod:            runningPod("crash", corev1.PodRunning, false, waiting("app", "CrashLoopBackOff", 3)),
+			expectedCode:   ExitFailing,
+			failsOnError:   true,
+			failsOnWarning: true,
+		},
+		{
+			name:           "Recovered after restarts",
+			pod:            runningPod("flaky", corev1.PodRunning, true, running("app", 2, true)),
+			expectedCode:   ExitDegraded,
+			failsOnWarning: true,
+		},
+		{
+			name:           "Unschedulable",
+			pod:            runningPod("pending", corev1.PodPending, false),
+			expectedCode:   ExitPending,
+			failsOnWarning: true,
+		},
+		{
+			name:         "Healthy",
+			pod:          runningPod("ok", corev1.PodRunning, true, running("app", 0, true)),
+			expectedCode: ExitHealthy,
+		},
+		{
+			name:         "Ignored sidecar restarted",
+			pod:          runningPod("web", corev1.PodRunning, true, running("app", 0, true), running("istio-proxy", 2, true)),
+			config:       &Config{IgnoreContainers: []string{"istio-proxy"}},
+			expectedCode: ExitHealthy,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failed, _ := identifyFailedContainers(tt.pod, false, tt.config)
+			outcome := podOutcome(tt.pod, failed, tt.config)
+
+			code := ExitHealthy
+			if outcome != nil {
+				code = outcome.Code
+			}
+			if code != tt.expectedCode {
+				t.Errorf("podOutcome() code = %d, want %d", code, tt.expectedCode)
+			}
+			if result := failsOn(outcome, FailOnError); result != tt.failsOnError {
+				t.Errorf("failsOn(error) = %v, want %v", result, tt.failsOnError)
+			}
+			if result := failsOn(outcome, FailOnWarning); result != tt.failsOnWarning {
+				t.Errorf("failsOn(warning) = %v, want %v", result, tt.failsOnWarning)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package plugin
+
+import (
+	"regexp"
+)
+
+// DefaultFoldScanLines is how many log lines are fetched per stream when
+// folding, so the folded view can still fill --lines with distinct content
+const DefaultFoldScanLines int64 = 500
+
+// foldPatterns replace the parts of a log line that change between otherwise
+// identical lines, most specific first
+var foldPatterns = []struct {
+	regexp      *regexp.Regexp
+	placeholder string
+}{
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?`), "<ts>"},
+	{regexp.MustCompile(`\b\d{2}:\d{2}:\d{2}(?:[.,]\d+)?\b`), "<ts>"},
+	{regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`), "<uuid>"},
+	{regexp.MustCompile(`(?i)\b0x[0-9a-f]+\b`), "<hex>"},
+	{regexp.MustCompile(`\b[0-9a-f]*[0-9][0-9a-f]*\b`), "<hex>"},
+	{regexp.MustCompile(`\d+(?:\.\d+)?`), "<n>"},
+}
+
+// foldKey normalises timestamps, UUIDs, hex ids and numbers so lines that
+// differ only in those compare equal
+func foldKey(line string) string {
+	for _, pattern := range foldPatterns {
+		line = pattern.regexp.ReplaceAllString(line, pattern.placeholder)
+	}
+	return line
+}
+
+// foldRuns groups consecutive lines with the same fold key
+// It returns, for each run, the index of its last line and its length; the
+// last line is kept because it carries the most recent timestamp and values
+func foldRuns(lines []string) (last []int, counts []int) {
+	previousKey := ""
+	for i, line := range lines {
+		key := foldKey(line)
+		if i > 0 && key == previousKey {
+			last[len(last)-1] = i
+			counts[len(counts)-1]++
+			continue
+		}
+		last = append(last, i)
+		counts = append(counts, 1)
+		previousKey = key
+	}
+	return last, counts
+}
@@ -0,0 +1,82 @@
+package plugin
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestFoldKey tests normalisation of the parts that vary between repeated lines
+func TestFoldKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		expected bool
+	}{
+		{
+			name:     "Timestamps and counters differ",
+			a:        "2024-05-01T12:00:00.120Z retry 3: connection refused to db:5432",
+			b:        "2024-05-01T12:00:05.981Z retry 4: connection refused to db:5432",
+			expected: true,
+		},
+		{
+			name:     "Request ids differ",
+			a:        "request 5f0c2a9e-8d1b-4c3e-9f7a-0b1c2d3e4f5a failed after 0x1f ms",
+			b:        "request 0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d failed after 0x2a ms",
+			expected: true,
+		},
+		{
+			name:     "Different messages",
+			a:        "connection refused to db:5432",
+			b:        "connection reset by db:5432",
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := foldKey(tt.a) == foldKey(tt.b); result != tt.expected {
+				t.Errorf("foldKey(%q) == foldKey(%q) is %v, want %v", foldKey(tt.a), foldKey(tt.b), result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestRenderLogsFolds tests that folding keeps the cause within --lines
+func TestRenderLogsFolds(t *testing.T) {
+	logs := "config loaded\nmigration failed: column \"email\" already exists\n"
+	for i := 1; i <= 300; i++ {
+		logs += fmt.Sprintf("12:00:%02d retry %d: connection refused to db:5432\n", i%60, i)
+	}
+	logs += "shutting down\n"
+
+	opts := &TriageOptions{Lines: 3, NoColor: true}
+	output := renderLogLines(t, logs, opts)
+
+	expected := "  migration failed: column \"email\" already exists\n" +
+		"  [x300] 12:00:00 retry 300: connection refused to db:5432\n" +
+		"  shutting down\n"
+	if output != expected {
+		t.Errorf("logLines() =\n%s\nwant\n%s", output, expected)
+	}
+
+	opts.NoFold = true
+	output = renderLogLines(t, logs, opts)
+	if strings.Contains(output, "[x") || strings.Count(output, "\n") != 3 {
+		t.Errorf("logLines() with NoFold =\n%s", output)
+	}
+}
+
+// renderLogLines renders the lines of a log section as the text output shows them
+func renderLogLines(t *testing.T, logs string, opts *TriageOptions) string {
+	t.Helper()
+
+	var out bytes.Buffer
+	text := &textWriter{w: &out}
+	text.logLines(logSection(logs, nil, opts, newLogLimits(opts)))
+	if text.err != nil {
+		t.Fatalf("logLines() error = %v", text.err)
+	}
+	return out.String()
+}
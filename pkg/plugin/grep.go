@@ -0,0 +1,84 @@
+package plugin
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// grepLine is a line shown by --grep, numbered from the start of the log
+type grepLine struct {
+	Number int
+	Text   string
+	Match  bool
+	// Gap is set on the first line of a region that doesn't follow the previous one
+	Gap bool
+}
+
+// grepRegions returns the lines matching re with before and after lines of
+// context; overlapping regions are merged
+func grepRegions(logs string, re *regexp.Regexp, before, after int) []grepLine {
+	lines := strings.Split(strings.TrimSuffix(logs, "\n"), "\n")
+
+	var result []grepLine
+	next := 0 // first line not yet shown
+	lastShown := -1
+	for i, line := range lines {
+		if !re.MatchString(strings.TrimRight(line, "\r")) {
+			continue
+		}
+
+		start := i - before
+		if start < next {
+			start = next
+		}
+		if start < 0 {
+			start = 0
+		}
+		end := i + after
+		if end >= len(lines) {
+			end = len(lines) - 1
+		}
+
+		for j := start; j <= end; j++ {
+			text := strings.TrimRight(lines[j], "\r")
+			result = append(result, grepLine{
+				Number: j + 1,
+				Text:   text,
+				Match:  re.MatchString(text),
+				Gap:    lastShown >= 0 && j != lastShown+1,
+			})
+			lastShown = j
+		}
+		next = end + 1
+	}
+	return result
+}
+
+// grepLogLines returns the --grep matches of a log with their context lines
+func grepLogLines(logs string, opts *TriageOptions) ([]LogLine, error) {
+	re, err := regexp.Compile(opts.Grep)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []LogLine
+	for _, match := range grepRegions(logs, re, opts.GrepBefore, opts.GrepAfter) {
+		lines = append(lines, LogLine{
+			Text:      match.Text,
+			Number:    match.Number,
+			Count:     1,
+			Highlight: match.Match,
+			Gap:       match.Gap,
+		})
+	}
+	return lines, nil
+}
+
+// grepPrefix numbers a --grep line: "12:" for a match, "11-" for context
+func (l LogLine) grepPrefix() string {
+	if l.Highlight {
+		return fmt.Sprintf("%d:", l.Number)
+	}
+	return fmt.Sprintf("%d-", l.Number)
+}
@@ -0,0 +1,68 @@
+package plugin
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// TestGrepRegions tests match selection, context lines and region merging
+func TestGrepRegions(t *testing.T) {
+	logs := "start\nconnect db\nERROR timeout\nretry\nretry\nretry\nERROR timeout\nstop\n"
+
+	tests := []struct {
+		name          string
+		pattern       string
+		before, after int
+		expected      []grepLine
+	}{
+		{
+			name:    "Matches only",
+			pattern: "ERROR",
+			expected: []grepLine{
+				{Number: 3, Text: "ERROR timeout", Match: true},
+				{Number: 7, Text: "ERROR timeout", Match: true, Gap: true},
+			},
+		},
+		{
+			name:    "Context around separate regions",
+			pattern: "ERROR",
+			before:  1,
+			after:   1,
+			expected: []grepLine{
+				{Number: 2, Text: "connect db"},
+				{Number: 3, Text: "ERROR timeout", Match: true},
+				{Number: 4, Text: "retry"},
+				{Number: 6, Text: "retry", Gap: true},
+				{Number: 7, Text: "ERROR timeout", Match: true},
+				{Number: 8, Text: "stop"},
+			},
+		},
+		{
+			name:    "Overlapping regions merged",
+			pattern: "ERROR",
+			after:   4,
+			expected: []grepLine{
+				{Number: 3, Text: "ERROR timeout", Match: true},
+				{Number: 4, Text: "retry"},
+				{Number: 5, Text: "retry"},
+				{Number: 6, Text: "retry"},
+				{Number: 7, Text: "ERROR timeout", Match: true},
+				{Number: 8, Text: "stop"},
+			},
+		},
+		{
+			name:    "No match",
+			pattern: "panic",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := grepRegions(logs, regexp.MustCompile(tt.pattern), tt.before, tt.after)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("grepRegions() = %+v, want %+v", result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package plugin
+
+import (
+	"html/template"
+	"io"
+	"time"
+)
+
+// htmlTimeFormat is how times are shown in the HTML report
+const htmlTimeFormat = "2006-01-02 15:04:05 MST"
+
+// renderHTML writes the report as a single HTML page with inline styles and
+// no external assets, so it opens in any browser and can be attached as is
+func renderHTML(w io.Writer, report *Report) error {
+	return htmlReport.Execute(w, report)
+}
+
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"time": func(t time.Time) string {
+		return t.UTC().Format(htmlTimeFormat)
+	},
+	"ago": func(t time.Time) string {
+		return formatDuration(time.Since(t).Round(time.Second))
+	},
+	"lineClass":    lineClass,
+	"displayLines": displayLines,
+	"section": func(title string, section LogSection) htmlLogSection {
+		return htmlLogSection{Title: title, LogSection: section}
+	},
+	"maxEvents": func(events []EventInfo) []EventInfo {
+		if len(events) > maxEvents {
+			return events[:maxEvents]
+		}
+		return events
+	},
+}).Parse(htmlTemplate))
+
+// htmlLogSection gives a log section its heading in the template
+type htmlLogSection struct {
+	Title string
+	LogSection
+}
+
+// lineClass picks the CSS class of a log line as the text output picks its
+// color: highlight rules first, then the level, then keywords
+func lineClass(line LogLine) string {
+	switch {
+	case line.Color != "":
+		return "c-" + line.Color
+	case line.Level == LevelWarn:
+		return "warn"
+	case line.Highlight:
+		return "error"
+	}
+	return ""
+}
+
+const htmlTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Triage report:{{range .Pods}} {{.Namespace}}/{{.Name}}{{end}}</title>
+<style>
+body { font: 14px/1.5 -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; color: #1f2328; margin: 0 auto; max-width: 1200px; padding: 24px; }
+h1 { font-size: 22px; margin: 0 0 4px; }
+h2 { font-size: 18px; margin: 0 0 12px; }
+h3 { font-size: 15px; margin: 20px 0 8px; }
+.meta { color: #656d76; margin: 0 0 24px; }
+.partial { background: #fff8c5; border: 1px solid #d4a72c; border-radius: 6px; padding: 8px 12px; }
+.pod { border: 1px solid #d0d7de; border-left-width: 6px; border-radius: 6px; padding: 16px 20px; margin-bottom: 24px; }
+.pod.failing { border-left-color: #cf222e; }
+.pod.degraded { border-left-color: #d4a72c; }
+.pod.pending { border-left-color: #0969da; }
+.pod.healthy { border-left-color: #1a7f37; }
+.pod.error { border-left-color: #cf222e; color: inherit; font-weight: normal; }
+.badge { border-radius: 12px; color: #fff; font-size: 12px; padding: 2px 10px; text-transform: uppercase; vertical-align: middle; }
+.failing .badge { background: #cf222e; }
+.degraded .badge { background: #9a6700; }
+.pending .badge { background: #0969da; }
+.healthy .badge { background: #1a7f37; }
+.pod.error .badge { background: #cf222e; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #d0d7de; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f6f8fa; }
+.summary th, .summary td { width: auto; }
+details { border: 1px solid #d0d7de; border-radius: 6px; margin: 8px 0; }
+summary { background: #f6f8fa; cursor: pointer; font-weight: 600; padding: 6px 12px; }
+details .body { padding: 4px 12px 12px; }
+.note { color: #656d76; font-style: italic; margin: 6px 0; }
+pre { background: #0d1117; border-radius: 6px; color: #e6edf3; font: 12px/1.45 ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; margin: 6px 0; overflow-x: auto; padding: 10px 12px; }
+pre span { display: block; white-space: pre; }
+.error { color: #ff7b72; font-weight: 600; }
+.warn { color: #e3b341; }
+.c-red { color: #ff7b72; }
+.c-yellow { color: #e3b341; }
+.c-green { color: #7ee787; }
+.c-blue { color: #79c0ff; }
+.c-magenta { color: #d2a8ff; }
+.c-cyan { color: #a5d6ff; }
+.c-white { color: #ffffff; }
+.timeline { list-style: none; margin: 0; padding: 0; }
+.timeline li { border-left: 3px solid #d0d7de; padding: 2px 0 2px 12px; }
+.timeline li.error { border-left-color: #cf222e; color: #cf222e; font-weight: normal; }
+.timeline li.warn { border-left-color: #d4a72c; color: #9a6700; }
+.timeline time { color: #656d76; font-family: ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; margin-right: 8px; }
+.kind { color: #656d76; font-size: 12px; margin-right: 8px; text-transform: uppercase; }
+code { background: #f6f8fa; border-radius: 4px; padding: 1px 4px; }
+</style>
+</head>
+<body>
+<h1>Triage report</h1>
+<p class="meta">Generated {{time .Generated}} by kubectl-triage</p>
+{{- if .Partial}}
+<p class="partial"><strong>Partial results:</strong> {{.Partial}} before collection finished; incomplete sections are marked.</p>
+{{- end}}
+{{range .Pods}}
+<section class="pod {{.Status}}">
+<h2>Pod {{.Namespace}}/{{.Name}} <span class="badge">{{.Status}}</span></h2>
+{{- if ne .Status "error"}}
+<table class="summary">
+<tr><th>Phase</th><td>{{.Phase}}</td><th>Ready</th><td>{{.Ready}}</td><th>Restarts</th><td>{{.Restarts}}</td></tr>
+</table>
+{{- end}}
+{{- if .Message}}
+<p>{{.Message}}</p>
+{{- end}}
+{{- if .Skipped}}
+<p class="note">The pod is healthy and wasn't inspected; use --force to inspect it anyway.</p>
+{{- else}}
+{{- if .Events}}
+<h3>Events</h3>
+<table>
+<tr><th>Age</th><th>Type</th><th>Reason</th><th>Message</th></tr>
+{{- range maxEvents .Events}}
+<tr><td title="{{time .Timestamp}}">{{ago .Timestamp}}</td><td>{{.Type}}</td><td>{{.Reason}}</td><td>{{.Message}}</td></tr>
+{{- end}}
+</table>
+{{- else if .EventsError}}
+<h3>Events</h3>
+<p class="note">Events not fetched: {{.EventsError}}</p>
+{{- end}}
+{{- range .Containers}}{{if .Triaged}}
+<details open>
+<summary>Container {{.Name}}: {{.Status}}, {{.RestartCount}} restarts</summary>
+<div class="body">
+{{- if .Crash}}
+<h3>Crash ({{.Crash.Language}})</h3>
+<pre>{{range .CrashLines}}<span class="{{lineClass .}}">{{.Text}}</span>{{end}}</pre>
+{{- end}}
+{{- template "logs" section "Previous logs" .Previous}}
+{{- template "logs" section "Current logs" .Current}}
+</div>
+</details>
+{{- end}}{{end}}
+{{- $others := false}}{{range .Containers}}{{if not .Triaged}}{{$others = true}}{{end}}{{end}}
+{{- if $others}}
+<h3>Other containers</h3>
+<table>
+<tr><th>Container</th><th>State</th><th>Restarts</th></tr>
+{{- range .Containers}}{{if not .Triaged}}
+<tr><td>{{.Name}}{{if .Ignored}} (ignored){{end}}</td><td>{{.Status}}</td><td>{{.RestartCount}}</td></tr>
+{{- end}}{{end}}
+</table>
+{{- end}}
+{{- if .Timeline}}
+<h3>Timeline</h3>
+<ol class="timeline">
+{{- range .Timeline}}
+<li class="{{.Level}}"><time>{{time .Time}}</time><span class="kind">{{.Kind}}</span>{{.Text}}</li>
+{{- end}}
+</ol>
+{{- end}}
+{{- if .NextSteps}}
+<h3>Next steps</h3>
+<ul>
+{{- range .NextSteps}}
+<li>{{.Title}}{{if .Commands}}<pre>{{range .Commands}}<span>{{.}}</span>{{end}}</pre>{{end}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .Redacted}}
+<p class="note">Redacted {{.Redacted}} item(s): {{.RedactedSummary}}</p>
+{{- end}}
+{{- end}}
+</section>
+{{end}}
+</body>
+</html>
+{{- define "logs"}}{{if not .Empty}}
+<h3>{{.Title}}{{if .Incomplete}} (incomplete){{end}}: {{.Window}}</h3>
+{{- if and .Error (not .Incomplete)}}
+<p class="note">Not available: {{.Error}}</p>
+{{- else}}
+{{- if .Incomplete}}
+<p class="note">Log fetch cut short</p>
+{{- end}}
+{{- if .CutAtBytes}}
+<p class="note">Log cut at {{.CutAtBytes}} bytes</p>
+{{- end}}
+{{- if .Hidden}}
+<p class="note">{{.Hidden}} line(s) below the minimum level hidden</p>
+{{- end}}
+{{- if .Omitted}}
+<p class="note">{{.Omitted}} earlier line(s) omitted to fit the output budget</p>
+{{- end}}
+{{- if .Lines}}
+<pre>{{range displayLines .Lines}}<span class="{{lineClass .}}">{{.Text}}</span>{{end}}</pre>
+{{- end}}
+{{- end}}
+{{- end}}{{end}}
+`
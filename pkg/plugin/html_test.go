@@ -0,0 +1,72 @@
+package plugin
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderHTML(t *testing.T) {
+	crashed := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	report := &Report{
+		Generated: crashed.Add(time.Minute),
+		Pods: []PodReport{{
+			Name:      "api-1",
+			Namespace: "prod",
+			Phase:     "Running",
+			Ready:     "0/1",
+			Status:    StatusFailing,
+			Message:   "pod api-1 is failing: container app is Waiting (CrashLoopBackOff)",
+			Events:    []EventInfo{{Type: "Warning", Reason: "BackOff", Message: "Back-off restarting failed container", Timestamp: crashed}},
+			Containers: []ContainerReport{
+				{
+					Name:    "app",
+					Reason:  "CrashLoopBackOff",
+					Triaged: true,
+					Previous: LogSection{Window: "Last 50 lines", Lines: []LogLine{
+						{Text: "serving <script>alert(1)</script>"},
+						{Text: "WARN slow request", Level: LevelWarn},
+						{Text: "ERROR: database connection failed", Highlight: true},
+						{Text: "payment declined", Color: "magenta"},
+					}},
+				},
+				{Name: "sidecar", State: "Running"},
+			},
+			Timeline: []TimelineEntry{
+				{Time: crashed, Kind: TimelineContainer, Level: LevelError, Text: "Container app terminated: Error (exit code 1)"},
+			},
+			NextSteps: []NextStep{{Title: "Roll back", Commands: []string{"kubectl rollout undo deployment/api -n prod"}}},
+		}},
+	}
+
+	var out bytes.Buffer
+	if err := renderHTML(&out, report); err != nil {
+		t.Fatal(err)
+	}
+	page := out.String()
+
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		`<section class="pod failing">`,
+		"<summary>Container app: CrashLoopBackOff, 0 restarts</summary>",
+		"serving &lt;script&gt;alert(1)&lt;/script&gt;",
+		`<span class="warn">WARN slow request</span>`,
+		`<span class="error">ERROR: database connection failed</span>`,
+		`<span class="c-magenta">payment declined</span>`,
+		`<li class="error"><time>2024-03-01 12:00:00 UTC</time>`,
+		"<td>sidecar</td><td>Running</td>",
+		"kubectl rollout undo deployment/api -n prod",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page lacks %q", want)
+		}
+	}
+
+	// The page must open offline
+	for _, external := range []string{"<script", "<link", "src=", "@import", "url("} {
+		if strings.Contains(page, external) {
+			t.Errorf("page references an external asset: %q", external)
+		}
+	}
+}
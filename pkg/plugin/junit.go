@@ -0,0 +1,244 @@
+package plugin
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// JUnit XML as read by CI systems (Jenkins, GitLab, GitHub test reporters)
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// writeJUnit writes a JUnit report with a test case per container of each
+// pod; pods the run didn't get to, e.g. after a timeout, are errors
+func writeJUnit(path, namespace string, pods []*corev1.Pod, results []*podTriage, opts *TriageOptions, reason string) error {
+	target := opts.Selector
+	if target == "" {
+		names := make([]string, len(pods))
+		for i, pod := range pods {
+			names[i] = pod.Name
+		}
+		target = strings.Join(names, ",")
+	}
+
+	report := buildJUnit(namespace+"/"+target, pods, results, opts, reason)
+	report.Suites[0].Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode JUnit report: %w", err)
+	}
+	data = append([]byte(xml.Header), append(data, '\n')...)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write JUnit report: %w", err)
+	}
+	return nil
+}
+
+// writeJUnitError writes a report whose only test case is the error that
+// ended the triage before its pods were triaged, e.g. a pod that wasn't found
+func writeJUnitError(path, namespace string, opts *TriageOptions, err error) error {
+	target := opts.Selector
+	if target == "" {
+		target = strings.Join(append([]string{opts.PodName}, opts.PodNames...), ",")
+	}
+	stub := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: target, Namespace: namespace}}
+	return writeJUnit(path, namespace, []*corev1.Pod{stub}, []*podTriage{{Pod: stub, Err: err}}, opts, "")
+}
+
+// buildJUnit turns the triaged pods into a single test suite
+func buildJUnit(name string, pods []*corev1.Pod, results []*podTriage, opts *TriageOptions, reason string) *junitTestSuites {
+	suite := junitTestSuite{Name: name}
+	var total time.Duration
+
+	for i, pod := range pods {
+		className := pod.Namespace + "." + pod.Name
+		if i >= len(results) {
+			suite.Cases = append(suite.Cases, junitTestCase{
+				ClassName: className,
+				Name:      "pod",
+				Time:      junitSeconds(0),
+				Error:     &junitProblem{Message: "not triaged: " + reason, Type: "Incomplete"},
+			})
+			continue
+		}
+
+		result := results[i]
+		total += result.Duration
+		suite.Cases = append(suite.Cases, podTestCases(className, result, opts)...)
+	}
+
+	for _, tc := range suite.Cases {
+		suite.Tests++
+		switch {
+		case tc.Failure != nil:
+			suite.Failures++
+		case tc.Error != nil:
+			suite.Errors++
+		case tc.Skipped != nil:
+			suite.Skipped++
+		}
+	}
+	suite.Time = junitSeconds(total)
+
+	return &junitTestSuites{
+		Name:     "kubectl-triage",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Errors:   suite.Errors,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+}
+
+// podTestCases reports each container of a pod; a pod whose problem lies
+// outside its containers, e.g. one that can't be scheduled, gets a "pod" case
+func podTestCases(className string, result *podTriage, opts *TriageOptions) []junitTestCase {
+	if result.Err != nil {
+		return []junitTestCase{{
+			ClassName: className,
+			Name:      "pod",
+			Time:      junitSeconds(result.Duration),
+			Error:     &junitProblem{Message: result.Err.Error(), Type: "Error"},
+		}}
+	}
+	containers, _ := identifyFailedContainers(result.Pod, true, opts.Config)
+	elapsed := junitSeconds(result.Duration)
+
+	var cases []junitTestCase
+	containerFailed := false
+	for _, container := range containers {
+		tc := junitTestCase{ClassName: className, Name: container.Name, Time: elapsed}
+		switch {
+		case container.Ignored:
+			tc.Skipped = &junitSkipped{Message: "ignored by config"}
+		case !container.Failed:
+		case container.State != "Running":
+			reason := container.Reason
+			if reason == "" {
+				reason = container.State
+			}
+			tc.Failure = &junitProblem{
+				Message: fmt.Sprintf("container %s is %s (%s), %d restarts", container.Name, container.State, reason, container.RestartCount),
+				Type:    reason,
+				Body:    failureDetails(result, container.Name, opts.Lines),
+			}
+		case opts.FailOn != FailOnError:
+			tc.Failure = &junitProblem{
+				Message: fmt.Sprintf("container %s restarted %d time(s)", container.Name, container.RestartCount),
+				Type:    "Restarted",
+				Body:    failureDetails(result, container.Name, opts.Lines),
+			}
+		}
+		if tc.Failure != nil {
+			containerFailed = true
+		}
+		cases = append(cases, tc)
+	}
+
+	if !containerFailed && failsOn(result.Outcome, opts.FailOn) {
+		cases = append(cases, junitTestCase{
+			ClassName: className,
+			Name:      "pod",
+			Time:      elapsed,
+			Failure: &junitProblem{
+				Message: result.Outcome.Message,
+				Type:    string(result.Pod.Status.Phase),
+				Body:    failureDetails(result, "", opts.Lines),
+			},
+		})
+	}
+	return cases
+}
+
+// failureDetails lists the pod's events and the container's crash trace and
+// log tail as the body of a failure
+func failureDetails(result *podTriage, container string, lines int64) string {
+	var b strings.Builder
+
+	if len(result.Events) > 0 {
+		b.WriteString("Events:\n")
+		for _, event := range result.Events {
+			fmt.Fprintf(&b, "  %s %s %s: %s\n", event.Timestamp.UTC().Format(time.RFC3339), event.Type, event.Reason, sanitizeLine(event.Message))
+		}
+	}
+
+	for _, logs := range result.Logs {
+		if logs.ContainerName != container {
+			continue
+		}
+		if logs.Crash != nil {
+			b.WriteString("Crash (" + logs.Crash.Language + "):\n")
+			for _, line := range logs.Crash.Lines {
+				b.WriteString("  " + sanitizeLine(line) + "\n")
+			}
+		}
+		writeLogTail(&b, "Previous logs", logs.Previous, lines)
+		writeLogTail(&b, "Current logs", logs.Current, lines)
+	}
+	return b.String()
+}
+
+func writeLogTail(b *strings.Builder, title, logs string, lines int64) {
+	logLines := strings.Split(strings.TrimRight(logs, "\n"), "\n")
+	if strings.TrimSpace(logs) == "" {
+		return
+	}
+	if lines > 0 && int64(len(logLines)) > lines {
+		logLines = logLines[int64(len(logLines))-lines:]
+	}
+
+	fmt.Fprintf(b, "%s (last %d lines):\n", title, len(logLines))
+	for _, line := range logLines {
+		b.WriteString("  " + sanitizeLine(line) + "\n")
+	}
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
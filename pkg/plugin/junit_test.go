@@ -0,0 +1,256 @@
+package plugin
+
+import (
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+)
+
+// TestRunPluginJUnit triages the pods matching a selector and checks the JUnit report
+func TestRunPluginJUnit(t *testing.T) {
+	crashing := runningPod("web-1", corev1.PodRunning, false, waiting("app", "CrashLoopBackOff", 4))
+	crashing.Labels = map[string]string{"app": "web"}
+	healthy := runningPod("web-2", corev1.PodRunning, true, running("app", 0, true))
+	healthy.Labels = map[string]string{"app": "web"}
+	other := runningPod("db-1", corev1.PodRunning, false, waiting("db", "CrashLoopBackOff", 2))
+	event := podEvent(crashing, "Warning", "BackOff", "Back-off restarting failed container", time.Minute)
+
+	clientset := newFakeClientset(map[string]containerLogs{
+		"app": {Previous: "starting\npanic: config missing"},
+	}, crashing, healthy, other, &event)
+	report := filepath.Join(t.TempDir(), "report.xml")
+	opts := &TriageOptions{
+		Namespace: testNamespace,
+		Selector:  "app=web",
+		JUnitFile: report,
+		Lines:     50,
+		NoColor:   true,
+		NewClient: func(*genericclioptions.ConfigFlags) (kubernetes.Interface, error) {
+			return clientset, nil
+		},
+	}
+
+	output, runErr := runPlugin(opts)
+	if code := ExitCode(runErr); code != ExitFailing {
+		t.Errorf("RunPlugin() error = %v, exit code %d; want %d", runErr, code, ExitFailing)
+	}
+	if strings.Contains(output, "db-1") || !strings.Contains(output, "📦 POD triage-test/web-1") {
+		t.Errorf("output should cover the matching pods only:\n%s", output)
+	}
+
+	data, err := ioutil.ReadFile(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var suites junitTestSuites
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		t.Fatalf("invalid JUnit XML: %v\n%s", err, data)
+	}
+	if suites.Tests != 2 || suites.Failures != 1 || len(suites.Suites) != 1 {
+		t.Fatalf("got %d tests, %d failures in %d suites; want 2 tests, 1 failure in 1 suite", suites.Tests, suites.Failures, len(suites.Suites))
+	}
+
+	suite := suites.Suites[0]
+	if suite.Name != "triage-test/app=web" {
+		t.Errorf("suite name = %q", suite.Name)
+	}
+	failed := suite.Cases[0]
+	if failed.ClassName != "triage-test.web-1" || failed.Name != "app" || failed.Failure == nil {
+		t.Fatalf("first test case = %+v, want failure of triage-test.web-1/app", failed)
+	}
+	if failed.Failure.Type != "CrashLoopBackOff" {
+		t.Errorf("failure type = %q, want CrashLoopBackOff", failed.Failure.Type)
+	}
+	for _, want := range []string{"Back-off restarting failed container", "panic: config missing"} {
+		if !strings.Contains(failed.Failure.Body, want) {
+			t.Errorf("failure body lacks %q:\n%s", want, failed.Failure.Body)
+		}
+	}
+	if passed := suite.Cases[1]; passed.ClassName != "triage-test.web-2" || passed.Failure != nil {
+		t.Errorf("second test case = %+v, want web-2 passing", passed)
+	}
+}
+
+// TestRunPluginJUnitRedacted checks that a JUnit report, redacted by default,
+// doesn't carry the secrets in the logs
+func TestRunPluginJUnitRedacted(t *testing.T) {
+	pod := runningPod("web-1", corev1.PodRunning, false, waiting("app", "CrashLoopBackOff", 4))
+	clientset := newFakeClientset(map[string]containerLogs{
+		"app": {Previous: "calling billing with Authorization: Bearer s3cr3t-t0ken\npanic: unauthorized"},
+	}, pod)
+	report := filepath.Join(t.TempDir(), "report.xml")
+	opts := &TriageOptions{
+		PodName:   pod.Name,
+		Namespace: testNamespace,
+		JUnitFile: report,
+		Redact:    RedactsByDefault(OutputText, report),
+		Lines:     50,
+		NoColor:   true,
+		NewClient: func(*genericclioptions.ConfigFlags) (kubernetes.Interface, error) {
+			return clientset, nil
+		},
+	}
+
+	runPlugin(opts)
+	data, err := ioutil.ReadFile(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "s3cr3t-t0ken") || !strings.Contains(string(data), "Bearer [REDACTED:bearer-token]") {
+		t.Errorf("JUnit report doesn't mask the bearer token:\n%s", data)
+	}
+}
+
+// TestRunPluginJUnitNotFound checks that a run ended by a missing pod still
+// writes a JUnit report, with the error as its test case
+func TestRunPluginJUnitNotFound(t *testing.T) {
+	tests := []struct {
+		name      string
+		podName   string
+		selector  string
+		suite     string
+		className string
+		message   string
+	}{
+		{
+			name:      "pod name",
+			podName:   "missing",
+			suite:     "triage-test/missing",
+			className: "triage-test.missing",
+			message:   "failed to get pod missing in namespace triage-test",
+		},
+		{
+			name:      "selector",
+			selector:  "app=gone",
+			suite:     "triage-test/app=gone",
+			className: "triage-test.app=gone",
+			message:   "app=gone",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := newFakeClientset(nil)
+			report := filepath.Join(t.TempDir(), "report.xml")
+			opts := &TriageOptions{
+				PodName:   tt.podName,
+				Selector:  tt.selector,
+				Namespace: testNamespace,
+				JUnitFile: report,
+				Lines:     50,
+				NoColor:   true,
+				NewClient: func(*genericclioptions.ConfigFlags) (kubernetes.Interface, error) {
+					return clientset, nil
+				},
+			}
+
+			_, runErr := runPlugin(opts)
+			if code := ExitCode(runErr); code != ExitNotFound {
+				t.Errorf("RunPlugin() error = %v, exit code %d; want %d", runErr, code, ExitNotFound)
+			}
+
+			data, err := ioutil.ReadFile(report)
+			if err != nil {
+				t.Fatalf("no JUnit report written: %v", err)
+			}
+			var suites junitTestSuites
+			if err := xml.Unmarshal(data, &suites); err != nil {
+				t.Fatalf("invalid JUnit XML: %v\n%s", err, data)
+			}
+			if suites.Tests != 1 || suites.Errors != 1 || len(suites.Suites) != 1 || len(suites.Suites[0].Cases) != 1 {
+				t.Fatalf("got %d tests, %d errors; want a single error:\n%s", suites.Tests, suites.Errors, data)
+			}
+			suite := suites.Suites[0]
+			tc := suite.Cases[0]
+			if suite.Name != tt.suite || tc.ClassName != tt.className || tc.Name != "pod" || tc.Error == nil {
+				t.Fatalf("suite %q, test case %+v; want an error for %s/pod in suite %s", suite.Name, tc, tt.className, tt.suite)
+			}
+			if !strings.Contains(tc.Error.Message, tt.message) {
+				t.Errorf("error message = %q, want it to mention %q", tc.Error.Message, tt.message)
+			}
+		})
+	}
+}
+
+func TestPodTestCases(t *testing.T) {
+	tests := []struct {
+		name   string
+		pod    *corev1.Pod
+		failOn string
+		// err is why the pod couldn't be triaged
+		err error
+		// want lists each test case as name=outcome
+		want []string
+	}{
+		{
+			name: "crash-looping container fails",
+			pod:  runningPod("p", corev1.PodRunning, false, waiting("app", "CrashLoopBackOff", 3), running("sidecar", 0, true)),
+			want: []string{"app=failure:CrashLoopBackOff", "sidecar=pass"},
+		},
+		{
+			name: "restarted container fails on warning",
+			pod:  runningPod("p", corev1.PodRunning, true, running("app", 2, true)),
+			want: []string{"app=failure:Restarted"},
+		},
+		{
+			name:   "restarted container passes on error",
+			pod:    runningPod("p", corev1.PodRunning, true, running("app", 2, true)),
+			failOn: FailOnError,
+			want:   []string{"app=pass"},
+		},
+		{
+			name: "unscheduled pod fails as a whole",
+			pod:  runningPod("p", corev1.PodPending, false),
+			want: []string{"pod=failure:Pending"},
+		},
+		{
+			name: "ignored container is skipped",
+			pod:  runningPod("p", corev1.PodRunning, false, waiting("istio-proxy", "CrashLoopBackOff", 3)),
+			want: []string{"istio-proxy=skipped"},
+		},
+		{
+			name: "restarted ignored container doesn't fail the pod",
+			pod:  runningPod("p", corev1.PodRunning, true, running("app", 0, true), running("istio-proxy", 2, true)),
+			want: []string{"app=pass", "istio-proxy=skipped"},
+		},
+		{
+			name: "pod that couldn't be triaged is an error",
+			pod:  runningPod("p", corev1.PodRunning, false, waiting("app", "CrashLoopBackOff", 3)),
+			err:  errors.New("failed to get events: connection refused"),
+			want: []string{"pod=error:Error"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &TriageOptions{Lines: 50, FailOn: tt.failOn, Config: &Config{IgnoreContainers: []string{"istio-proxy"}}}
+			failed, _ := identifyFailedContainers(tt.pod, false, opts.Config)
+			result := &podTriage{Pod: tt.pod, Outcome: podOutcome(tt.pod, failed, opts.Config), Err: tt.err}
+
+			var got []string
+			for _, tc := range podTestCases("ns.p", result, opts) {
+				outcome := "pass"
+				switch {
+				case tc.Failure != nil:
+					outcome = "failure:" + tc.Failure.Type
+				case tc.Error != nil:
+					outcome = "error:" + tc.Error.Type
+				case tc.Skipped != nil:
+					outcome = "skipped"
+				}
+				got = append(got, tc.Name+"="+outcome)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("podTestCases() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultLimitBytes caps how much of a single log stream is fetched
+	DefaultLimitBytes int64 = 2 * 1024 * 1024
+
+	// DefaultMaxLineWidth is the number of characters shown before a line is cut
+	DefaultMaxLineWidth = 400
+
+	// DefaultMaxOutputBytes caps the log text printed across all containers
+	DefaultMaxOutputBytes = 64 * 1024
+)
+
+// logLimits bounds how much log text a single triage prints
+type logLimits struct {
+	limitBytes   int64 // fetch cap per log stream, used to flag cut logs
+	maxLineWidth int   // 0 means lines are never cut
+	remaining    int   // bytes of log text left to print; negative means unlimited
+}
+
+// newLogLimits builds the print limits for a triage run
+// --full-lines turns off both line cutting and the output budget
+func newLogLimits(opts *TriageOptions) *logLimits {
+	if opts.FullLines {
+		return &logLimits{limitBytes: opts.LimitBytes, remaining: -1}
+	}
+
+	limits := &logLimits{
+		limitBytes:   opts.LimitBytes,
+		maxLineWidth: opts.MaxLineWidth,
+		remaining:    opts.MaxOutputBytes,
+	}
+	if limits.remaining <= 0 {
+		limits.remaining = -1
+	}
+	return limits
+}
+
+// splitLogLines splits a log into its non-empty lines
+func splitLogLines(logs string) []string {
+	var lines []string
+	for _, line := range strings.Split(logs, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// fit cuts long lines in place and drops the oldest lines that don't fit the
+// remaining output budget, returning the index of the first line to print
+func (l *logLimits) fit(lines []string) int {
+	for i, line := range lines {
+		lines[i] = truncateLine(sanitizeLine(line), l.maxLineWidth)
+	}
+
+	if l.remaining < 0 {
+		return 0
+	}
+
+	// Keep the newest lines: the end of a log is where a crash shows up
+	start := len(lines)
+	for start > 0 && len(lines[start-1])+1 <= l.remaining {
+		start--
+		l.remaining -= len(lines[start]) + 1
+	}
+	if start > 0 {
+		// Nothing more fits after a cut, later sections are omitted entirely
+		l.remaining = 0
+	}
+
+	return start
+}
+
+// fitLines cuts long lines and drops the oldest lines that don't fit the
+// output budget, returning the lines to show
+func (l *logLimits) fitLines(lines []LogLine) (shown []LogLine, omitted int) {
+	texts := make([]string, len(lines))
+	for i, line := range lines {
+		texts[i] = line.Text
+	}
+	start := l.fit(texts)
+	for i := range lines {
+		lines[i].Text = texts[i]
+	}
+	return lines[start:], start
+}
+
+// truncateLine cuts a line to at most width characters, noting how much was dropped
+func truncateLine(line string, width int) string {
+	if width <= 0 || utf8.RuneCountInString(line) <= width {
+		return line
+	}
+
+	runes := []rune(line)
+	return fmt.Sprintf("%s … [+%d chars]", string(runes[:width]), len(runes)-width)
+}
+
+// sanitizeLine replaces control characters and invalid UTF-8 so binary output
+// can't garble the terminal
+func sanitizeLine(line string) string {
+	return strings.Map(func(r rune) rune {
+		if r == unicode.ReplacementChar || (unicode.IsControl(r) && r != '\t') {
+			return unicode.ReplacementChar
+		}
+		return r
+	}, line)
+}
+
+// hitLimitBytes reports whether a fetched log was cut at the --limit-bytes cap
+func (l *logLimits) hitLimitBytes(logs string) bool {
+	return l.limitBytes > 0 && int64(len(logs)) >= l.limitBytes
+}
@@ -0,0 +1,109 @@
+package plugin
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestTruncateLine tests cutting of over-long log lines
+func TestTruncateLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		width    int
+		expected string
+	}{
+		{
+			name:     "Short line untouched",
+			line:     "connection refused",
+			width:    40,
+			expected: "connection refused",
+		},
+		{
+			name:     "Long line cut with indicator",
+			line:     strings.Repeat("x", 15),
+			width:    10,
+			expected: "xxxxxxxxxx … [+5 chars]",
+		},
+		{
+			name:     "Multi-byte characters counted as one",
+			line:     "héllo wörld",
+			width:    5,
+			expected: "héllo … [+6 chars]",
+		},
+		{
+			name:     "Zero width disables cutting",
+			line:     strings.Repeat("x", 1000),
+			width:    0,
+			expected: strings.Repeat("x", 1000),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := truncateLine(tt.line, tt.width)
+			if result != tt.expected {
+				t.Errorf("truncateLine() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestSanitizeLine tests replacement of binary content
+func TestSanitizeLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected string
+	}{
+		{
+			name:     "Plain text untouched",
+			line:     "level=error\tmsg=boom",
+			expected: "level=error\tmsg=boom",
+		},
+		{
+			name:     "Control characters replaced",
+			line:     "abc\x00\x1b[2Jdef",
+			expected: "abc��[2Jdef",
+		},
+		{
+			name:     "Invalid UTF-8 replaced",
+			line:     "ok\xffok",
+			expected: "ok�ok",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := sanitizeLine(tt.line)
+			if result != tt.expected {
+				t.Errorf("sanitizeLine() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestLogLimitsFit tests the output budget across several log sections
+func TestLogLimitsFit(t *testing.T) {
+	limits := newLogLimits(&TriageOptions{MaxLineWidth: 10, MaxOutputBytes: 12})
+
+	// Each line costs its length plus a newline, so only the last two fit
+	lines := splitLogLines("one\ntwo\nthree\r\n\n")
+	if start := limits.fit(lines); !reflect.DeepEqual(lines[start:], []string{"two", "three"}) || start != 1 {
+		t.Errorf("fit() kept %v from %d; want [two three] from 1", lines[start:], start)
+	}
+
+	// The budget is spent, so the next section is omitted entirely
+	lines = splitLogLines("four\nfive\n")
+	if start := limits.fit(lines); start != 2 {
+		t.Errorf("fit() after budget kept %v, want none", lines[start:])
+	}
+
+	// --full-lines lifts every limit
+	full := newLogLimits(&TriageOptions{MaxLineWidth: 3, MaxOutputBytes: 1, FullLines: true})
+	lines = splitLogLines("a long line\nanother\n")
+	if start := full.fit(lines); !reflect.DeepEqual(lines, []string{"a long line", "another"}) || start != 0 {
+		t.Errorf("fit() with full lines kept %v from %d", lines[start:], start)
+	}
+}
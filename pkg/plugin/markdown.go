@@ -0,0 +1,184 @@
+package plugin
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// renderMarkdown writes the report as GitHub/GitLab flavored markdown, with
+// the crash and each log in a collapsible <details> block
+func renderMarkdown(w io.Writer, report *Report) error {
+	var b strings.Builder
+	if report.Partial != "" {
+		fmt.Fprintf(&b, "> **Partial results:** %s before collection finished; incomplete sections are marked.\n\n", report.Partial)
+	}
+	for i, pod := range report.Pods {
+		if i > 0 {
+			b.WriteString("---\n\n")
+		}
+		writeMarkdownPod(&b, pod)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeMarkdownPod(b *strings.Builder, pod PodReport) {
+	fmt.Fprintf(b, "## Pod `%s` in `%s`: %s\n\n", pod.Name, pod.Namespace, pod.Status)
+	if pod.Status == StatusError {
+		b.WriteString("Couldn't triage the pod: " + markdownEscape(pod.Message) + "\n\n")
+		return
+	}
+	fmt.Fprintf(b, "**Phase:** %s | **Ready:** %s | **Restarts:** %d\n\n", pod.Phase, pod.Ready, pod.Restarts)
+	if pod.Skipped {
+		b.WriteString("The pod is healthy and wasn't inspected; use `--force` to inspect it anyway.\n\n")
+		return
+	}
+	if pod.Message != "" {
+		b.WriteString(markdownEscape(pod.Message) + "\n\n")
+	}
+
+	if len(pod.Events) > 0 {
+		b.WriteString("### Events\n\n")
+		b.WriteString("| Age | Type | Reason | Message |\n")
+		b.WriteString("| --- | --- | --- | --- |\n")
+		for i, event := range pod.Events {
+			if i == maxEvents {
+				break
+			}
+			fmt.Fprintf(b, "| %s | %s | %s | %s |\n",
+				formatDuration(time.Since(event.Timestamp).Round(time.Second)),
+				event.Type, markdownCell(event.Reason), markdownCell(event.Message))
+		}
+		b.WriteString("\n")
+	} else if pod.EventsError != "" {
+		fmt.Fprintf(b, "### Events\n\n_Events not fetched: %s_\n\n", markdownEscape(pod.EventsError))
+	}
+
+	for _, container := range pod.triagedContainers() {
+		fmt.Fprintf(b, "### Container `%s`: %s, %d restarts\n\n", container.Name, container.Status(), container.RestartCount)
+
+		if container.Crash != nil {
+			writeMarkdownDetails(b, fmt.Sprintf("Crash (%s)", container.Crash.Language), nil, logLineTexts(container.CrashLines))
+		}
+		writeMarkdownLogs(b, "Previous logs", container.Previous)
+		writeMarkdownLogs(b, "Current logs", container.Current)
+	}
+
+	if others := pod.otherContainers(); len(others) > 0 {
+		fmt.Fprintf(b, "**Other containers:** %s\n\n", markdownEscape(strings.Join(others, ", ")))
+	}
+
+	if len(pod.NextSteps) > 0 {
+		b.WriteString("### Next steps\n\n")
+		for _, step := range pod.NextSteps {
+			b.WriteString("- " + markdownEscape(step.Title) + "\n")
+			if len(step.Commands) > 0 {
+				fence := markdownFence(step.Commands)
+				b.WriteString("  " + fence + "shell\n")
+				for _, command := range step.Commands {
+					b.WriteString("  " + command + "\n")
+				}
+				b.WriteString("  " + fence + "\n")
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	if pod.Redacted > 0 {
+		fmt.Fprintf(b, "_Redacted %d item(s): %s_\n\n", pod.Redacted, pod.RedactedSummary)
+	}
+}
+
+func writeMarkdownLogs(b *strings.Builder, title string, section LogSection) {
+	if section.Empty() {
+		return
+	}
+	if section.Error != "" && !section.Incomplete {
+		fmt.Fprintf(b, "**%s:** _not available: %s_\n\n", title, markdownEscape(section.Error))
+		return
+	}
+
+	var notes []string
+	if section.Incomplete {
+		title += " (incomplete)"
+		notes = append(notes, "log fetch cut short")
+	}
+	if section.CutAtBytes > 0 {
+		notes = append(notes, fmt.Sprintf("log cut at %d bytes", section.CutAtBytes))
+	}
+	if section.Hidden > 0 {
+		notes = append(notes, fmt.Sprintf("%d line(s) below the minimum level hidden", section.Hidden))
+	}
+	if section.Omitted > 0 {
+		notes = append(notes, fmt.Sprintf("%d earlier line(s) omitted to fit the output budget", section.Omitted))
+	}
+	writeMarkdownDetails(b, fmt.Sprintf("%s: %s", title, section.Window), notes, logLineTexts(section.Lines))
+}
+
+// writeMarkdownDetails writes a collapsible block with the lines in a code fence
+func writeMarkdownDetails(b *strings.Builder, summary string, notes, lines []string) {
+	fmt.Fprintf(b, "<details>\n<summary>%s</summary>\n\n", htmlEscaper.Replace(summary))
+	for _, note := range notes {
+		b.WriteString("_" + note + "_\n\n")
+	}
+	if len(lines) > 0 {
+		fence := markdownFence(lines)
+		b.WriteString(fence + "text\n")
+		for _, line := range lines {
+			b.WriteString(line + "\n")
+		}
+		b.WriteString(fence + "\n\n")
+	}
+	b.WriteString("</details>\n\n")
+}
+
+// logLineTexts returns the text of log lines laid out by displayLines
+func logLineTexts(lines []LogLine) []string {
+	var texts []string
+	for _, line := range displayLines(lines) {
+		texts = append(texts, line.Text)
+	}
+	return texts
+}
+
+// markdownFence returns a code fence longer than any run of backticks in lines
+func markdownFence(lines []string) string {
+	longest := 0
+	for _, line := range lines {
+		run := 0
+		for _, c := range line {
+			if c != '`' {
+				run = 0
+				continue
+			}
+			run++
+			if run > longest {
+				longest = run
+			}
+		}
+	}
+	if longest < 3 {
+		return "```"
+	}
+	return strings.Repeat("`", longest+1)
+}
+
+var htmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// markdownEscaper keeps text from being read as markdown or HTML
+var markdownEscaper = strings.NewReplacer(
+	"&", "&amp;", "<", "&lt;", ">", "&gt;",
+	"\\", "\\\\", "`", "\\`", "*", "\\*", "_", "\\_", "[", "\\[", "]", "\\]", "|", "\\|",
+)
+
+func markdownEscape(text string) string {
+	return markdownEscaper.Replace(sanitizeLine(text))
+}
+
+// markdownCell escapes text for a table cell, which must stay on one line
+func markdownCell(text string) string {
+	return markdownEscape(strings.Join(strings.Fields(text), " "))
+}
@@ -0,0 +1,42 @@
+package plugin
+
+import "testing"
+
+func TestMarkdownFence(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  string
+	}{
+		{name: "plain text", lines: []string{"starting", "ERROR: failed"}, want: "```"},
+		{name: "inline code", lines: []string{"run `make`"}, want: "```"},
+		{name: "fenced block in the log", lines: []string{"```json", "{}", "```"}, want: "````"},
+		{name: "longer fence in the log", lines: []string{"`````"}, want: "``````"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := markdownFence(tt.lines); got != tt.want {
+				t.Errorf("markdownFence() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdownCell(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "Back-off restarting failed container", want: "Back-off restarting failed container"},
+		{input: "probe failed: a | b", want: "probe failed: a \\| b"},
+		{input: "line one\nline two", want: "line one line two"},
+		{input: "<script> *bold* _it_", want: "&lt;script&gt; \\*bold\\* \\_it\\_"},
+	}
+
+	for _, tt := range tests {
+		if got := markdownCell(tt.input); got != tt.want {
+			t.Errorf("markdownCell(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
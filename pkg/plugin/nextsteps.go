@@ -0,0 +1,198 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+// defaultMemoryLimit is suggested for OOMKilled containers that have no limit or request
+const defaultMemoryLimit = "512Mi"
+
+// NextStep is a suggested remediation with ready-to-run commands
+type NextStep struct {
+	Title    string
+	Commands []string
+}
+
+// kubectlCommand builds kubectl commands scoped to the triaged pod's namespace and context
+type kubectlCommand struct {
+	namespace string
+	context   string
+}
+
+func newKubectlCommand(namespace string, configFlags *genericclioptions.ConfigFlags) kubectlCommand {
+	cmd := kubectlCommand{namespace: namespace}
+	if configFlags != nil && configFlags.Context != nil {
+		cmd.context = *configFlags.Context
+	}
+	return cmd
+}
+
+// build quotes args and adds the namespace and context flags before any "--"
+func (k kubectlCommand) build(args ...string) string {
+	scope := []string{"-n", shellQuote(k.namespace)}
+	if k.context != "" {
+		scope = append(scope, "--context="+shellQuote(k.context))
+	}
+
+	parts := []string{"kubectl"}
+	for _, arg := range args {
+		if arg == "--" && scope != nil {
+			parts, scope = append(parts, scope...), nil
+		}
+		parts = append(parts, shellQuote(arg))
+	}
+	parts = append(parts, scope...)
+	return strings.Join(parts, " ")
+}
+
+// nextSteps derives remediation steps from the failure type of each failed container
+func nextSteps(pod *corev1.Pod, failedContainers []ContainerInfo, kubectl kubectlCommand) []NextStep {
+	var steps []NextStep
+	workloadKind, workloadName := podWorkload(pod)
+	failing := false
+
+	for _, container := range failedContainers {
+		if !container.Failed {
+			continue
+		}
+		failing = true
+		status := containerStatus(pod, container.Name)
+
+		switch {
+		case wasOOMKilled(status):
+			limit := suggestMemoryLimit(pod, container.Name)
+			step := NextStep{Title: fmt.Sprintf("Container '%s' was OOMKilled: raise its memory limit to %s", container.Name, limit)}
+			if workloadKind != "" && workloadKind != "job" {
+				step.Commands = append(step.Commands, kubectl.build("set", "resources", workloadKind+"/"+workloadName,
+					"-c", container.Name, "--limits=memory="+limit))
+			} else {
+				step.Title += " in the pod manifest"
+				step.Commands = append(step.Commands, kubectl.build("get", "pod", pod.Name, "-o", "yaml"))
+			}
+			steps = append(steps, step)
+
+		case container.Reason == "ImagePullBackOff" || container.Reason == "ErrImagePull" || container.Reason == "InvalidImageName":
+			step := NextStep{Title: fmt.Sprintf("Container '%s' can't pull %s: check the image name and the registry credentials", container.Name, containerImage(pod, container.Name))}
+			if len(pod.Spec.ImagePullSecrets) == 0 {
+				step.Commands = append(step.Commands, kubectl.build("get", "secret", "--field-selector=type=kubernetes.io/dockerconfigjson"))
+			}
+			for _, secret := range pod.Spec.ImagePullSecrets {
+				step.Commands = append(step.Commands, kubectl.build("get", "secret", secret.Name, "-o", "yaml"))
+			}
+			steps = append(steps, step)
+
+		case container.Reason == "CrashLoopBackOff":
+			// One copy per container, so the commands for several crashing ones don't clash
+			debugPod := pod.Name + "-" + container.Name + "-debug"
+			steps = append(steps, NextStep{
+				Title: fmt.Sprintf("Container '%s' keeps crashing: start a copy that sleeps instead, and run the entrypoint by hand", container.Name),
+				Commands: []string{
+					kubectl.build("debug", pod.Name, "--copy-to="+debugPod, "--container="+container.Name, "--", "sleep", "1d"),
+					kubectl.build("exec", "-it", debugPod, "-c", container.Name, "--", "sh"),
+				},
+			})
+		}
+	}
+
+	if failing && (workloadKind == "deployment" || workloadKind == "statefulset" || workloadKind == "daemonset") {
+		ref := workloadKind + "/" + workloadName
+		steps = append(steps, NextStep{
+			Title: fmt.Sprintf("If the failures started with the latest rollout of %s, roll it back", ref),
+			Commands: []string{
+				kubectl.build("rollout", "history", ref),
+				kubectl.build("rollout", "undo", ref),
+			},
+		})
+	}
+
+	return steps
+}
+
+// podWorkload returns the lower-case kind and name of the workload that
+// manages the pod, or empty strings for a bare pod
+// Deployments are recognised from the ReplicaSet name without an API call
+func podWorkload(pod *corev1.Pod) (string, string) {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Controller == nil || !*owner.Controller {
+			continue
+		}
+		switch owner.Kind {
+		case "ReplicaSet":
+			if hash := pod.Labels["pod-template-hash"]; hash != "" && strings.HasSuffix(owner.Name, "-"+hash) {
+				return "deployment", strings.TrimSuffix(owner.Name, "-"+hash)
+			}
+			return "replicaset", owner.Name
+		case "StatefulSet", "DaemonSet", "Job":
+			return strings.ToLower(owner.Kind), owner.Name
+		}
+	}
+	return "", ""
+}
+
+func containerStatus(pod *corev1.Pod, name string) *corev1.ContainerStatus {
+	for i := range pod.Status.ContainerStatuses {
+		if pod.Status.ContainerStatuses[i].Name == name {
+			return &pod.Status.ContainerStatuses[i]
+		}
+	}
+	return nil
+}
+
+// wasOOMKilled checks the current state and, for a container already
+// restarted into CrashLoopBackOff, the last termination
+func wasOOMKilled(status *corev1.ContainerStatus) bool {
+	if status == nil {
+		return false
+	}
+	if status.State.Terminated != nil && status.State.Terminated.Reason == "OOMKilled" {
+		return true
+	}
+	return status.LastTerminationState.Terminated != nil && status.LastTerminationState.Terminated.Reason == "OOMKilled"
+}
+
+// suggestMemoryLimit doubles the container's memory limit, or its request
+// when it has no limit
+func suggestMemoryLimit(pod *corev1.Pod, name string) string {
+	for _, container := range pod.Spec.Containers {
+		if container.Name != name {
+			continue
+		}
+		current, ok := container.Resources.Limits[corev1.ResourceMemory]
+		if !ok {
+			current, ok = container.Resources.Requests[corev1.ResourceMemory]
+		}
+		if !ok || current.IsZero() {
+			break
+		}
+		mebibytes := (current.Value()*2 + (1<<20 - 1)) >> 20
+		return resource.NewQuantity(mebibytes<<20, resource.BinarySI).String()
+	}
+	return defaultMemoryLimit
+}
+
+func containerImage(pod *corev1.Pod, name string) string {
+	for _, container := range pod.Spec.Containers {
+		if container.Name == name {
+			return fmt.Sprintf("%q", container.Image)
+		}
+	}
+	return "its image"
+}
+
+// shellQuote quotes arg for POSIX shells when it holds anything beyond safe characters
+func shellQuote(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+	for _, c := range arg {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_./=:,@%+", c)) {
+			return "'" + strings.Replace(arg, "'", `'"'"'`, -1) + "'"
+		}
+	}
+	return arg
+}
@@ -0,0 +1,123 @@
+package plugin
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestNextSteps tests the remediation commands for each failure type
+func TestNextSteps(t *testing.T) {
+	controller := true
+	deploymentPod := func(statuses ...corev1.ContainerStatus) *corev1.Pod {
+		pod := runningPod("api-7d9f8b6c5-x2k4q", corev1.PodRunning, false, statuses...)
+		pod.Labels = map[string]string{"pod-template-hash": "7d9f8b6c5"}
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "api-7d9f8b6c5", Controller: &controller}}
+		pod.Spec.Containers = []corev1.Container{{
+			Name:  "app",
+			Image: "registry.example.com/api:v2",
+			Resources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("300Mi")},
+			},
+		}}
+		return pod
+	}
+
+	oomLoop := waiting("app", "CrashLoopBackOff", 3)
+	oomLoop.LastTerminationState.Terminated = &corev1.ContainerStateTerminated{Reason: "OOMKilled"}
+
+	bare := runningPod("worker", corev1.PodPending, false, waiting("app", "ImagePullBackOff", 0))
+	bare.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "regcred"}}
+
+	tests := []struct {
+		name     string
+		pod      *corev1.Pod
+		kubectl  kubectlCommand
+		expected []NextStep
+	}{
+		{
+			name:    "OOMKilled before CrashLoopBackOff in a deployment",
+			pod:     deploymentPod(oomLoop),
+			kubectl: kubectlCommand{namespace: "prod", context: "eu west"},
+			expected: []NextStep{
+				{
+					Title:    "Container 'app' was OOMKilled: raise its memory limit to 600Mi",
+					Commands: []string{"kubectl set resources deployment/api -c app --limits=memory=600Mi -n prod --context='eu west'"},
+				},
+				{
+					Title: "If the failures started with the latest rollout of deployment/api, roll it back",
+					Commands: []string{
+						"kubectl rollout history deployment/api -n prod --context='eu west'",
+						"kubectl rollout undo deployment/api -n prod --context='eu west'",
+					},
+				},
+			},
+		},
+		{
+			name:    "CrashLoopBackOff",
+			pod:     deploymentPod(waiting("app", "CrashLoopBackOff", 3)),
+			kubectl: kubectlCommand{namespace: "prod"},
+			expected: []NextStep{
+				{
+					Title: "Container 'app' keeps crashing: start a copy that sleeps instead, and run the entrypoint by hand",
+					Commands: []string{
+						"kubectl debug api-7d9f8b6c5-x2k4q --copy-to=api-7d9f8b6c5-x2k4q-app-debug --container=app -n prod -- sleep 1d",
+						"kubectl exec -it api-7d9f8b6c5-x2k4q-app-debug -c app -n prod -- sh",
+					},
+				},
+				{
+					Title: "If the failures started with the latest rollout of deployment/api, roll it back",
+					Commands: []string{
+						"kubectl rollout history deployment/api -n prod",
+						"kubectl rollout undo deployment/api -n prod",
+					},
+				},
+			},
+		},
+		{
+			name:    "ImagePullBackOff in a bare pod",
+			pod:     bare,
+			kubectl: kubectlCommand{namespace: "batch"},
+			expected: []NextStep{
+				{
+					Title:    "Container 'app' can't pull its image: check the image name and the registry credentials",
+					Commands: []string{"kubectl get secret regcred -o yaml -n batch"},
+				},
+			},
+		},
+		{
+			name:    "CrashLoopBackOff in two containers of a bare pod",
+			pod:     runningPod("worker", corev1.PodRunning, false, waiting("app", "CrashLoopBackOff", 3), waiting("sidecar", "CrashLoopBackOff", 2)),
+			kubectl: kubectlCommand{namespace: "batch"},
+			expected: []NextStep{
+				{
+					Title: "Container 'app' keeps crashing: start a copy that sleeps instead, and run the entrypoint by hand",
+					Commands: []string{
+						"kubectl debug worker --copy-to=worker-app-debug --container=app -n batch -- sleep 1d",
+						"kubectl exec -it worker-app-debug -c app -n batch -- sh",
+					},
+				},
+				{
+					Title: "Container 'sidecar' keeps crashing: start a copy that sleeps instead, and run the entrypoint by hand",
+					Commands: []string{
+						"kubectl debug worker --copy-to=worker-sidecar-debug --container=sidecar -n batch -- sleep 1d",
+						"kubectl exec -it worker-sidecar-debug -c sidecar -n batch -- sh",
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failed, _ := identifyFailedContainers(tt.pod, false, nil)
+			result := nextSteps(tt.pod, failed, tt.kubectl)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("nextSteps() = %#v, want %#v", result, tt.expected)
+			}
+		})
+	}
+}
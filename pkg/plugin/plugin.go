@@ -2,17 +2,25 @@ package plugin
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/Lc-Lin/kubectl-triage/pkg/logger"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport"
 )
 
 // TriageOptions holds configuration for the triage command
@@ -23,8 +31,90 @@ type TriageOptions struct {
 	AllContainers bool
 	Force         bool
 	NoColor       bool
+
+	// Timeout bounds the whole triage; zero means no limit
+	Timeout time.Duration
+
+	// LimitBytes caps each fetched log stream; zero means no cap
+	LimitBytes int64
+	// MaxLineWidth cuts longer log lines; zero means no cut
+	MaxLineWidth int
+	// MaxOutputBytes caps the log text printed across all containers; zero means no cap
+	MaxOutputBytes int
+	// FullLines disables line cutting and the output budget
+	FullLines bool
+
+	// LogKeys are extra field names tried before DefaultLogKeys in JSON and logfmt lines
+	LogKeys LogKeys
+	// MinLevel hides structured log lines below this level (debug, info, warn, error, fatal)
+	MinLevel string
+	// RawLogs prints JSON and logfmt lines as-is
+	RawLogs bool
+
+	// Grep shows only the lines matching this regular expression, searched
+	// across the whole current and previous logs
+	Grep string
+	// GrepBefore and GrepAfter are the context lines shown around each match
+	GrepBefore int
+	GrepAfter  int
+
+	// NoFold shows repeated log lines one by one instead of as "[x312] line"
+	NoFold bool
+	// FoldScanLines is how many lines are fetched per log stream when folding,
+	// so the folded view still fills Lines with distinct content
+	FoldScanLines int64
+
+	// Redact masks secrets and PII in logs and events before they are shown
+	Redact bool
+
+	// FailOn is the least severe outcome that returns an error: FailOnWarning
+	// (the default when empty) or FailOnError
+	FailOn string
+
+	// Output is the format of the triage: OutputText (the default when empty),
+	// OutputMarkdown, OutputSlack, OutputHTML, or a template or columns spec
+	// such as "go-template={{.Name}}" or "custom-columns=POD:.Name"
+	Output string
+
+	// PodNames are more pods to triage after PodName; each is looked up on its
+	// own, so one that can't be found or triaged doesn't stop the others
+	PodNames []string
+	// Selector triages every pod matching this label selector instead of PodName
+	Selector string
+	// JUnitFile, when set, receives a JUnit XML report with a test case per container
+	JUnitFile string
+
+	// Width is the terminal width the text output is laid out for; zero
+	// means unknown, and event messages aren't wrapped
+	Width int
+	// ASCII leaves emoji and other symbols out of the text output
+	ASCII bool
+	// Compact fits the text output of each failed container into about 25 lines
+	Compact bool
+
+	// Concurrency is how many pods are triaged, and API requests kept in
+	// flight, at a time; zero means DefaultConcurrency
+	Concurrency int
+	// QPS and Burst limit the API requests per second of a kubeconfig client;
+	// zero keeps the client-go defaults
+	QPS   float32
+	Burst int
+
+	// Out receives the triage output; nil means os.Stdout
+	Out io.Writer
+
+	// Config holds highlight rules, failure reasons and ignored containers
+	// from triage.yaml files; nil means built-in behaviour only
+	Config *Config
+
+	// NewClient builds the Kubernetes client; when nil a clientset is
+	// created from the kubeconfig resolved by the config flags
+	NewClient ClientFactory
 }
 
+// ClientFactory builds the Kubernetes client used by RunPlugin
+type ClientFactory func(configFlags *genericclioptions.ConfigFlags) (kubernetes.Interface, error)
+
 // ContainerInfo holds information about a container's state
 type ContainerInfo struct {
 	Name         string
@@ -32,6 +122,8 @@ type ContainerInfo struct {
 	Reason       string
 	RestartCount int32
 	Failed       bool
+	// Ignored is set for containers listed in the config's ignoreContainers
+	Ignored bool
 }
 
 // LogResult holds log output for a container
@@ -41,6 +133,8 @@ type LogResult struct {
 	Current       string
 	PreviousError error
 	CurrentError  error
+	// Crash is the last stack trace found in the previous logs, if any
+	Crash *StackTrace
 }
 
 // EventInfo holds simplified event information
@@ -52,58 +146,485 @@ type EventInfo struct {
 }
 
 // RunPlugin is the main entry point for the triage command
-func RunPlugin(configFlags *genericclioptions.ConfigFlags, opts *TriageOptions) error {
-	config, err := configFlags.ToRESTConfig()
+// When ctx ends or opts.Timeout expires before collection finishes, whatever
+// was gathered is still displayed, marked as partial, and an error is returned
+func RunPlugin(ctx context.Context, configFlags *genericclioptions.ConfigFlags, opts *TriageOptions) error {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	renderer, err := newRenderer(opts)
 	if err != nil {
-		return fmt.Errorf("failed to read kubeconfig: %w", err)
+		return fmt.Errorf("invalid output format: %w", err)
+	}
+	out := opts.Out
+	if out == nil {
+		out = os.Stdout
 	}
 
-	clientset, err := kubernetes.NewForConfig(config)
+	// -v reports each API call and where the time went
+	log := logger.NewLogger()
+	timings := &runTimings{start: time.Now()}
+	var wrap transport.WrapperFunc
+	if log.Level >= logger.LevelVerbose && opts.NewClient == nil {
+		timings.trace = newAPITrace(log)
+		wrap = timings.trace.wrap
+	}
+	defer timings.report(log)
+
+	// A CI gate that asked for a JUnit report gets one however the run ends
+	namespace := opts.namespace(configFlags)
+	fail := func(err error) error {
+		if opts.JUnitFile != "" {
+			if junitErr := writeJUnitError(opts.JUnitFile, namespace, opts, err); junitErr != nil {
+				log.Warn("%v", junitErr)
+			}
+		}
+		return err
+	}
+
+	clientset, err := opts.client(configFlags, wrap)
 	if err != nil {
-		return fmt.Errorf("failed to create clientset: %w", err)
+		return fail(err)
 	}
 
-	// Determine namespace
-	namespace := opts.Namespace
-	if namespace == "" {
-		if configFlags.Namespace != nil && *configFlags.Namespace != "" {
-			namespace = *configFlags.Namespace
-		} else {
-			namespace = "default"
+	pool := newWorkerPool(opts.Concurrency)
+	targets, err := targetPods(ctx, clientset, pool, namespace, opts)
+	if err != nil {
+		return fail(err)
+	}
+	timings.lookup = time.Since(timings.start)
+
+	// Sections RBAC forbids are skipped with a note rather than failing the
+	// triage; several pods share one list of the namespace's Warning events
+	run := &triageRun{clientset: clientset, pool: pool, allowed: checkAccess(ctx, clientset, namespace)}
+	if len(targets) > 1 {
+		run.events = &eventIndex{}
+	}
+
+	// Pods are triaged in parallel, but text is rendered pod by pod in the
+	// order given as soon as a pod and those before it are done; other
+	// formats are rendered once everything is collected
+	_, streaming := renderer.(*TextRenderer)
+	kubectl := newKubectlCommand(namespace, configFlags)
+	triageStart := time.Now()
+	triaged := make([]*podTriage, len(targets))
+	done := forEach(ctx, len(targets), opts.Concurrency, func(i int) {
+		if ctx.Err() != nil {
+			return
+		}
+		triaged[i] = run.triageTarget(ctx, targets[i], opts)
+	})
+
+	var results []*podTriage
+	var worst *OutcomeError
+	var podErr error
+	failedPods := 0
+	pods := make([]*corev1.Pod, len(targets))
+	for i, target := range targets {
+		pods[i] = target.pod
+	}
+	for i := range targets {
+		<-done[i]
+		result := triaged[i]
+		if result == nil {
+			// ctx ended before the pod was started, so none after it were either
+			break
+		}
+		if result.Err != nil {
+			// A lone pod fails the command as it did before anything was shown
+			if len(targets) == 1 {
+				return fail(result.Err)
+			}
+			failedPods++
+			if podErr == nil {
+				podErr = result.Err
+			}
+		}
+
+		pods[i] = result.Pod
+		results = append(results, result)
+		logPodTimings(log, result)
+		if streaming {
+			renderStart := time.Now()
+			if err := renderer.Render(out, buildReport([]*podTriage{result}, opts, kubectl, "")); err != nil {
+				return fail(fmt.Errorf("failed to write report: %w", err))
+			}
+			timings.render += time.Since(renderStart)
+		}
+		worst = worseOutcome(worst, result.Outcome)
+	}
+	timings.triage = time.Since(triageStart) - timings.render
+
+	partial := ""
+	if ctx.Err() != nil {
+		partial = interruptReason(ctx, opts.Timeout)
+	}
+	// Streamed text only has the partial results note left to show
+	report := &Report{Partial: partial, Generated: time.Now()}
+	if !streaming {
+		report = buildReport(results, opts, kubectl, partial)
+	}
+	if !streaming || partial != "" {
+		renderStart := time.Now()
+		if err := renderer.Render(out, report); err != nil {
+			return fail(fmt.Errorf("failed to write report: %w", err))
+		}
+		timings.render += time.Since(renderStart)
+	}
+
+	if opts.JUnitFile != "" {
+		if err := writeJUnit(opts.JUnitFile, namespace, pods, results, opts, interruptReason(ctx, opts.Timeout)); err != nil {
+			return err
+		}
+	}
+
+	if partial != "" {
+		return fmt.Errorf("triage incomplete: %w", &OutcomeError{Code: ExitPartial, Message: partial})
+	}
+
+	// The exit code of a pod that couldn't be triaged wins over the outcome
+	// of the others, so a missing pod isn't mistaken for a healthy one
+	if podErr != nil {
+		return fmt.Errorf("triage incomplete: %w", fmt.Errorf("%d of %d pods couldn't be triaged: %w", failedPods, len(pods), podErr))
+	}
+
+	if failsOn(worst, opts.FailOn) {
+		return worst
+	}
+	return nil
+}
+
+// podTriage holds everything collected for one pod
+type podTriage struct {
+	Pod *corev1.Pod
+	// Skipped is set for truly healthy pods, which aren't collected unless forced
+	Skipped bool
+
+	Failed    []ContainerInfo
+	Healthy   []ContainerInfo
+	Events    []EventInfo
+	EventsErr error
+	Logs      []LogResult
+	Redactor  *Redactor
+
+	// Err is why the pod couldn't be looked up or triaged; only Pod, which
+	// may just hold the name, and Duration are set along with it
+	Err error
+
+	// Outcome is nil for healthy pods
+	Outcome  *OutcomeError
+	Duration time.Duration
+	// EventsDuration and LogsDuration are how long the events list and the
+	// log streams took; they run in parallel
+	EventsDuration time.Duration
+	LogsDuration   time.Duration
+}
+
+// validate checks and normalises the options that RunPlugin parses
+func (o *TriageOptions) validate() error {
+	if o.MinLevel != "" {
+		level := normalizeLevel(o.MinLevel)
+		if level == "" {
+			return fmt.Errorf("invalid log level: %w", fmt.Errorf("unknown --min-level %q (use debug, info, warn, error or fatal)", o.MinLevel))
+		}
+		o.MinLevel = level
+	}
+
+	switch o.FailOn {
+	case "", FailOnWarning, FailOnError:
+	default:
+		return fmt.Errorf("invalid fail-on: %w", fmt.Errorf("unknown --fail-on %q (use warning or error)", o.FailOn))
+	}
+
+	if o.Grep != "" {
+		if _, err := regexp.Compile(o.Grep); err != nil {
+			return fmt.Errorf("invalid grep pattern: %w", fmt.Errorf("--grep %q: %v", o.Grep, err))
+		}
+	}
+
+	if (o.ASCII || o.Compact) && o.Output != "" && o.Output != OutputText {
+		return fmt.Errorf("invalid output: %w", fmt.Errorf("--ascii and --compact only apply to -o text, not %q", o.Output))
+	}
+	return nil
+}
+
+// podTarget is a pod to triage
+type podTarget struct {
+	// pod is the pod to triage, or when lookup is set one holding only the
+	// name and namespace, to be looked up when its turn comes
+	pod    *corev1.Pod
+	lookup bool
+}
+
+// targetPods returns the pods matching opts.Selector, or the pods named by
+// opts.PodName and opts.PodNames; a single pod is looked up right away, so
+// not finding it fails the triage, while several are looked up one by one
+// as they are triaged
+func targetPods(ctx context.Context, clientset kubernetes.Interface, pool *workerPool, namespace string, opts *TriageOptions) ([]podTarget, error) {
+	if opts.Selector != "" {
+		var pods *corev1.PodList
+		err := runWithContext(ctx, func() error {
+			list, err := clientset.CoreV1().Pods(namespace).List(metav1.ListOptions{LabelSelector: opts.Selector})
+			pods = list
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pods matching %s in namespace %s: %w", opts.Selector, namespace, forbidden(err, listPodsPermission, namespace))
+		}
+		if len(pods.Items) == 0 {
+			return nil, &OutcomeError{Code: ExitNotFound, Message: fmt.Sprintf("no pods match selector %s in namespace %s", opts.Selector, namespace)}
+		}
+
+		targets := make([]podTarget, len(pods.Items))
+		for i := range pods.Items {
+			targets[i] = podTarget{pod: &pods.Items[i]}
+		}
+		sort.Slice(targets, func(i, j int) bool { return targets[i].pod.Name < targets[j].pod.Name })
+		logger.NewLogger().Verbose("Found %d pod(s) matching %s in namespace %s", len(targets), opts.Selector, namespace)
+		return targets, nil
+	}
+
+	if len(opts.PodNames) == 0 {
+		pod, err := lookupPod(ctx, clientset, pool, namespace, opts.PodName, opts)
+		if err != nil {
+			return nil, err
 		}
+		return []podTarget{{pod: pod}}, nil
 	}
 
-	// Fetch the pod
-	pod, err := clientset.CoreV1().Pods(namespace).Get(opts.PodName, metav1.GetOptions{})
+	var targets []podTarget
+	for _, name := range append([]string{opts.PodName}, opts.PodNames...) {
+		if name == "" {
+			continue
+		}
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+		targets = append(targets, podTarget{pod: pod, lookup: true})
+	}
+	return targets, nil
+}
+
+// lookupPod gets a pod by name, or by a prefix or fuzzy match of its name
+func lookupPod(ctx context.Context, clientset kubernetes.Interface, pool *workerPool, namespace, name string, opts *TriageOptions) (*corev1.Pod, error) {
+	var pod *corev1.Pod
+	err := pool.do(ctx, func() error {
+		return runWithContext(ctx, func() error {
+			p, err := clientset.CoreV1().Pods(namespace).Get(name, metav1.GetOptions{})
+			pod = p
+			return err
+		})
+	})
+	if apierrors.IsNotFound(err) {
+		// Pod names carry random suffixes: try the name as a prefix or fuzzy match
+		pod, err = resolvePod(ctx, clientset, namespace, name, opts.Config, err)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get pod %s in namespace %s: %w", opts.PodName, namespace, err)
+		return nil, fmt.Errorf("failed to get pod %s in namespace %s: %w", name, namespace, forbidden(err, getPodsPermission, namespace))
 	}
+	return pod, nil
+}
+
+// triageRun holds what the pods of a run share while they are triaged
+type triageRun struct {
+	clientset kubernetes.Interface
+	pool      *workerPool
+	// allowed says which sections RBAC allows
+	allowed access
+	// events lists the Warning events of the namespace once for all the pods
+	// of a run; when nil the events of each pod are listed on their own
+	events *eventIndex
+}
+
+// triageTarget looks a target up if needed and triages it; errors are kept
+// in the result, so one pod doesn't stop the others
+func (r *triageRun) triageTarget(ctx context.Context, target podTarget, opts *TriageOptions) *podTriage {
+	start := time.Now()
+	pod := target.pod
+	if target.lookup {
+		found, err := lookupPod(ctx, r.clientset, r.pool, pod.Namespace, pod.Name, opts)
+		if err != nil {
+			return &podTriage{Pod: pod, Err: err, Duration: time.Since(start)}
+		}
+		pod = found
+	}
+
+	result, err := r.triagePod(ctx, pod, opts)
+	if err != nil {
+		return &podTriage{Pod: pod, Err: err, Duration: time.Since(start)}
+	}
+	result.Duration = time.Since(start)
+	return result
+}
+
+// triagePod collects the events and logs of a pod's failed containers
+// Truly healthy pods are skipped unless opts.Force is set; sections RBAC
+// forbids are skipped with a ForbiddenError in place of their content
+func (r *triageRun) triagePod(ctx context.Context, pod *corev1.Pod, opts *TriageOptions) (*podTriage, error) {
+	start := time.Now()
+	result := &podTriage{Pod: pod}
 
 	// Check if pod is truly healthy
-	if !opts.Force && isPodTrulyHealthy(pod) {
-		log := logger.NewLogger()
-		readyCount := getReadyCount(pod)
-		log.Info(fmt.Sprintf("✅ Pod '%s' is healthy (Ready %s, 0 restarts).", pod.Name, readyCount))
-		log.Info("Use --force to inspect anyway.")
-		return nil
+	if !opts.Force && isPodTrulyHealthy(pod, opts.Config) {
+		result.Skipped = true
+		return result, nil
 	}
 
 	// Identify failed containers
-	failedContainers, healthyContainers := identifyFailedContainers(pod, opts.AllContainers)
+	result.Failed, result.Healthy = identifyFailedContainers(pod, opts.AllContainers, opts.Config)
 
-	// Get relevant events (Warning/Error only)
-	events, err := getRelevantEvents(clientset, namespace, pod.Name)
-	if err != nil {
-		return fmt.Errorf("failed to get events: %w", err)
+	// Get relevant events (Warning/Error only) alongside the logs, so a slow
+	// events list doesn't eat into the time left for log collection
+	eventsDone := make(chan struct{})
+	go func() {
+		defer close(eventsDone)
+		if r.allowed.events != nil {
+			result.EventsErr = r.allowed.events
+			return
+		}
+		eventsStart := time.Now()
+		result.Events, result.EventsErr = r.podEvents(ctx, pod)
+		result.EventsErr = forbidden(result.EventsErr, listEventsPermission, pod.Namespace)
+		result.EventsDuration = time.Since(eventsStart)
+	}()
+
+	// Collect logs for failed containers in parallel, as the pool allows
+	logsStart := time.Now()
+	if r.allowed.logs != nil {
+		for _, container := range result.Failed {
+			result.Logs = append(result.Logs, LogResult{ContainerName: container.Name, PreviousError: r.allowed.logs, CurrentError: r.allowed.logs})
+		}
+	} else {
+		result.Logs = collectLogs(ctx, r.clientset, r.pool, pod.Namespace, pod.Name, result.Failed, opts.fetchLines(), opts.LimitBytes)
+		for i := range result.Logs {
+			result.Logs[i].PreviousError = forbidden(result.Logs[i].PreviousError, getLogsPermission, pod.Namespace)
+			result.Logs[i].CurrentError = forbidden(result.Logs[i].CurrentError, getLogsPermission, pod.Namespace)
+		}
+	}
+	result.LogsDuration = time.Since(logsStart)
+
+	<-eventsDone
+	var forbiddenErr *ForbiddenError
+	if result.EventsErr != nil && !isContextError(result.EventsErr) && !errors.As(result.EventsErr, &forbiddenErr) {
+		return nil, fmt.Errorf("failed to get events: %w", result.EventsErr)
+	}
+
+	log := logger.NewLogger()
+	log.Debug("Fetched %d relevant event(s) of pod %s", len(result.Events), pod.Name)
+	for _, logs := range result.Logs {
+		log.Debug("Fetched %d bytes of previous and %d bytes of current logs of container %s", len(logs.Previous), len(logs.Current), logs.ContainerName)
 	}
 
-	// Collect logs for failed containers in parallel
-	logResults := collectLogs(clientset, namespace, pod.Name, failedContainers, opts.Lines)
+	// Mask secrets before anything is rendered
+	if opts.Redact {
+		var extra []RedactRule
+		if opts.Config != nil {
+			extra = opts.Config.Redact
+		}
+		redactor, err := NewRedactor(extra)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact rule: %w", err)
+		}
+		redactResults(redactor, result.Events, result.Logs)
+		result.Redactor = redactor
+	}
 
-	// Display the triage output
-	displayTriage(pod, failedContainers, healthyContainers, events, logResults, opts)
+	result.Outcome = podOutcome(pod, result.Failed, opts.Config)
+	result.Duration = time.Since(start)
+	return result, nil
+}
 
-	return nil
+// client builds the Kubernetes client with NewClient, or from the kubeconfig
+// when unset, rate limited by QPS and Burst; wrap, when set, wraps the HTTP
+// transport of a kubeconfig client
+func (o *TriageOptions) client(configFlags *genericclioptions.ConfigFlags, wrap transport.WrapperFunc) (kubernetes.Interface, error) {
+	if o.NewClient != nil {
+		return o.NewClient(configFlags)
+	}
+	return newClientset(configFlags, func(config *rest.Config) {
+		if wrap != nil {
+			config.WrapTransport = transport.Wrappers(config.WrapTransport, wrap)
+		}
+		if o.QPS > 0 {
+			config.QPS = o.QPS
+		}
+		if o.Burst > 0 {
+			config.Burst = o.Burst
+		}
+	})
+}
+
+// namespace returns the namespace to triage in: Namespace, then the
+// --namespace flag, then "default"
+func (o *TriageOptions) namespace(configFlags *genericclioptions.ConfigFlags) string {
+	if o.Namespace != "" {
+		return o.Namespace
+	}
+	if configFlags.Namespace != nil && *configFlags.Namespace != "" {
+		return *configFlags.Namespace
+	}
+	return "default"
+}
+
+// runWithContext runs fn and returns early with the context error if ctx ends first.
+// The typed clients of this client-go version take no context, so a call that
+// outlives ctx is abandoned rather than cancelled
+func runWithContext(ctx context.Context, fn func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// isContextError reports whether err was caused by a cancelled or expired context
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// interruptReason describes why ctx ended, for partial-result notes
+func interruptReason(ctx context.Context, timeout time.Duration) string {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) && timeout > 0 {
+		return fmt.Sprintf("timed out after %s", timeout)
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "timed out"
+	}
+	return "interrupted"
+}
+
+// NewClientset is the default ClientFactory, building a clientset from the kubeconfig
+func NewClientset(configFlags *genericclioptions.ConfigFlags) (kubernetes.Interface, error) {
+	return newClientset(configFlags, nil)
+}
+
+// newClientset builds a clientset from the kubeconfig, after configure, when
+// set, adjusts its REST config
+func newClientset(configFlags *genericclioptions.ConfigFlags, configure func(*rest.Config)) (kubernetes.Interface, error) {
+	config, err := configFlags.ToRESTConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+	if configure != nil {
+		configure(config)
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clientset: %w", err)
+	}
+
+	return clientset, nil
 }
 
 // isPodTrulyHealthy checks if a pod is actually healthy
@@ -111,24 +632,28 @@ func RunPlugin(configFlags *genericclioptions.ConfigFlags, opts *TriageOptions)
 // 1. Phase is Running
 // 2. All containers are Ready
 // 3. RestartCount is 0 for all containers
-func isPodTrulyHealthy(pod *corev1.Pod) bool {
+// Containers the config ignores don't count; config can be nil
+func isPodTrulyHealthy(pod *corev1.Pod, config *Config) bool {
 	// Check 1: Phase must be Running
 	if pod.Status.Phase != corev1.PodRunning {
 		return false
 	}
 
-	// Check 2: All containers must be Ready
-	for _, condition := range pod.Status.Conditions {
-		if condition.Type == corev1.PodReady {
-			if condition.Status != corev1.ConditionTrue {
-				return false
-			}
+	// Check 2 and 3: containers must be Ready and never restarted
+	ignoredNotReady := false
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		if config.isIgnored(containerStatus.Name) {
+			ignoredNotReady = ignoredNotReady || !containerStatus.Ready
+			continue
+		}
+		if !containerStatus.Ready || containerStatus.RestartCount > 0 {
+			return false
 		}
 	}
 
-	// Check 3: RestartCount must be 0 for all containers
-	for _, containerStatus := range pod.Status.ContainerStatuses {
-		if containerStatus.RestartCount > 0 {
+	// The pod isn't Ready while an ignored container isn't, which doesn't count
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady && condition.Status != corev1.ConditionTrue && !ignoredNotReady {
 			return false
 		}
 	}
@@ -149,10 +674,23 @@ func getReadyCount(pod *corev1.Pod) string {
 }
 
 // identifyFailedContainers analyzes container statuses and categorizes them
-func identifyFailedContainers(pod *corev1.Pod, allContainers bool) ([]ContainerInfo, []ContainerInfo) {
+// config may add failure reasons and containers to ignore; it can be nil
+func identifyFailedContainers(pod *corev1.Pod, allContainers bool, config *Config) ([]ContainerInfo, []ContainerInfo) {
 	var failed []ContainerInfo
 	var healthy []ContainerInfo
 
+	waitingReasons := []string{
+		"CrashLoopBackOff", "Error", "ImagePullBackOff", "ErrImagePull",
+		"CreateContainerError", "InvalidImageName",
+	}
+	terminatedReasons := []string{
+		"Error", "OOMKilled", "ContainerCannotRun", "DeadlineExceeded",
+	}
+	if config != nil {
+		waitingReasons = append(waitingReasons, config.FailureReasons.Waiting...)
+		terminatedReasons = append(terminatedReasons, config.FailureReasons.Terminated...)
+	}
+
 	for _, cs := range pod.Status.ContainerStatuses {
 		info := ContainerInfo{
 			Name:         cs.Name,
@@ -170,11 +708,7 @@ func identifyFailedContainers(pod *corev1.Pod, allContainers bool) ([]ContainerI
 			info.State = "Waiting"
 			info.Reason = cs.State.Waiting.Reason
 			// Check for failure reasons
-			failureReasons := []string{
-				"CrashLoopBackOff", "Error", "ImagePullBackOff", "ErrImagePull",
-				"CreateContainerError", "InvalidImageName",
-			}
-			for _, reason := range failureReasons {
+			for _, reason := range waitingReasons {
 				if cs.State.Waiting.Reason == reason {
 					info.Failed = true
 					break
@@ -184,10 +718,7 @@ func identifyFailedContainers(pod *corev1.Pod, allContainers bool) ([]ContainerI
 			info.State = "Terminated"
 			info.Reason = cs.State.Terminated.Reason
 			// Check for failure reasons
-			failureReasons := []string{
-				"Error", "OOMKilled", "ContainerCannotRun", "DeadlineExceeded",
-			}
-			for _, reason := range failureReasons {
+			for _, reason := range terminatedReasons {
 				if cs.State.Terminated.Reason == reason {
 					info.Failed = true
 					break
@@ -198,7 +729,10 @@ func identifyFailedContainers(pod *corev1.Pod, allContainers bool) ([]ContainerI
 			info.Reason = ""
 		}
 
-		if info.Failed || allContainers {
+		// Ignored containers are only triaged when all containers are asked for
+		info.Ignored = config.isIgnored(cs.Name)
+
+		if allContainers || (info.Failed && !info.Ignored) {
 			failed = append(failed, info)
 		} else {
 			healthy = append(healthy, info)
@@ -209,16 +743,25 @@ func identifyFailedContainers(pod *corev1.Pod, allContainers bool) ([]ContainerI
 }
 
 // getRelevantEvents fetches only Warning and Error events for the pod
-func getRelevantEvents(clientset *kubernetes.Clientset, namespace, podName string) ([]EventInfo, error) {
-	eventList, err := clientset.CoreV1().Events(namespace).List(metav1.ListOptions{
-		FieldSelector: fmt.Sprintf("involvedObject.name=%s", podName),
+func getRelevantEvents(ctx context.Context, clientset kubernetes.Interface, namespace, podName string) ([]EventInfo, error) {
+	var eventList *corev1.EventList
+	err := runWithContext(ctx, func() error {
+		list, err := clientset.CoreV1().Events(namespace).List(metav1.ListOptions{
+			FieldSelector: fmt.Sprintf("involvedObject.name=%s", podName),
+		})
+		eventList = list
+		return err
 	})
 	if err != nil {
 		return nil, err
 	}
+	return relevantEvents(eventList.Items), nil
+}
 
+// relevantEvents keeps the Warning and Error events, most recent first
+func relevantEvents(events []corev1.Event) []EventInfo {
 	var relevantEvents []EventInfo
-	for _, event := range eventList.Items {
+	for _, event := range events {
 		// Only include Warning and Error type events (filter out Normal events)
 		if event.Type == corev1.EventTypeWarning || event.Type == "Error" {
 			relevantEvents = append(relevantEvents, EventInfo{
@@ -240,13 +783,24 @@ func getRelevantEvents(clientset *kubernetes.Clientset, namespace, podName strin
 		}
 	}
 
-	return relevantEvents, nil
+	return relevantEvents
 }
 
-// collectLogs fetches logs for failed containers in parallel
-func collectLogs(clientset *kubernetes.Clientset, namespace, podName string, containers []ContainerInfo, tailLines int64) []LogResult {
+// collectLogs fetches logs for failed containers in parallel, with no more
+// streams open at a time than the pool allows
+// A tailLines of zero or less fetches whole logs
+func collectLogs(ctx context.Context, clientset kubernetes.Interface, pool *workerPool, namespace, podName string, containers []ContainerInfo, tailLines, limitBytes int64) []LogResult {
 	var wg sync.WaitGroup
 	results := make([]LogResult, len(containers))
+	fetch := func(opts *corev1.PodLogOptions) (string, error) {
+		var logs string
+		err := pool.do(ctx, func() error {
+			var err error
+			logs, err = fetchLog(ctx, clientset, namespace, podName, opts)
+			return err
+		})
+		return logs, err
+	}
 
 	for i, container := range containers {
 		wg.Add(1)
@@ -255,20 +809,44 @@ func collectLogs(clientset *kubernetes.Clientset, namespace, podName string, con
 
 			result := LogResult{ContainerName: containerName}
 
-			// Fetch previous logs
+			// Fetch previous logs, reaching further back than the tail window so
+			// a stack trace that started earlier is still found whole
 			prevLogOpts := &corev1.PodLogOptions{
 				Container: containerName,
 				Previous:  true,
-				TailLines: &tailLines,
 			}
-			result.Previous, result.PreviousError = fetchLog(clientset, namespace, podName, prevLogOpts)
+			scanLines := tailLines
+			if tailLines > 0 {
+				if scanLines < crashScanLines {
+					scanLines = crashScanLines
+				}
+				prevLogOpts.TailLines = &scanLines
+			}
+			if limitBytes > 0 {
+				prevLogOpts.LimitBytes = &limitBytes
+			}
+			previous, previousErr := fetch(prevLogOpts)
+
+			// LimitBytes keeps the start of the window, so if the wider scan hit
+			// it the end of the crash is missing: fall back to the plain tail
+			if previousErr == nil && scanLines > tailLines && limitBytes > 0 && int64(len(previous)) >= limitBytes {
+				prevLogOpts.TailLines = &tailLines
+				previous, previousErr = fetch(prevLogOpts)
+			}
+			result.Crash = extractStackTrace(previous)
+			result.Previous, result.PreviousError = lastLines(previous, tailLines), previousErr
 
 			// Fetch current logs
 			currLogOpts := &corev1.PodLogOptions{
 				Container: containerName,
-				TailLines: &tailLines,
 			}
-			result.Current, result.CurrentError = fetchLog(clientset, namespace, podName, currLogOpts)
+			if tailLines > 0 {
+				currLogOpts.TailLines = &tailLines
+			}
+			if limitBytes > 0 {
+				currLogOpts.LimitBytes = &limitBytes
+			}
+			result.Current, result.CurrentError = fetch(currLogOpts)
 
 			results[idx] = result
 		}(i, container.Name)
@@ -279,16 +857,38 @@ func collectLogs(clientset *kubernetes.Clientset, namespace, podName string, con
 }
 
 // fetchLog retrieves log content from a pod
-func fetchLog(clientset *kubernetes.Clientset, namespace, podName string, opts *corev1.PodLogOptions) (string, error) {
-	req := clientset.CoreV1().Pods(namespace).GetLogs(podName, opts)
+// If ctx ends mid-stream, the lines read so far are returned with the context error
+func fetchLog(ctx context.Context, clientset kubernetes.Interface, namespace, podName string, opts *corev1.PodLogOptions) (string, error) {
+	req := clientset.CoreV1().Pods(namespace).GetLogs(podName, opts).Context(ctx)
 	podLogs, err := req.Stream()
 	if err != nil {
 		return "", err
 	}
 	defer podLogs.Close()
 
+	// Close the stream when ctx ends so a stalled read returns
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			podLogs.Close()
+		case <-stop:
+		}
+	}()
+
+	// The API server already honours LimitBytes; the reader cap guards against
+	// servers and proxies that don't
+	var body io.Reader = podLogs
+	if opts.LimitBytes != nil {
+		body = io.LimitReader(podLogs, *opts.LimitBytes)
+	}
+
 	buf := new(bytes.Buffer)
-	_, err = io.Copy(buf, podLogs)
+	_, err = io.Copy(buf, body)
+	if ctx.Err() != nil {
+		return buf.String(), ctx.Err()
+	}
 	if err != nil {
 		return "", err
 	}
@@ -296,130 +896,127 @@ func fetchLog(clientset *kubernetes.Clientset, namespace, podName string, opts *
 	return buf.String(), nil
 }
 
-// displayTriage outputs the formatted triage information
-func displayTriage(pod *corev1.Pod, failedContainers, healthyContainers []ContainerInfo, events []EventInfo, logResults []LogResult, opts *TriageOptions) {
-	log := logger.NewLogger()
-
-	// Display each failed container
-	for i, container := range failedContainers {
-		log.Info("")
-		log.Info(strings.Repeat("=", 80))
-		if container.Failed {
-			log.ErrorMsg(fmt.Sprintf("🚨 TRIAGE FOR FAILED CONTAINER: '%s' (Reason: %s)", container.Name, container.Reason))
-		} else {
-			log.Info(fmt.Sprintf("🔍 TRIAGE FOR CONTAINER: '%s'", container.Name))
-		}
-		log.Info(strings.Repeat("=", 80))
-		log.Info("")
-
-		// Pod Status Section
-		log.Info("📋 POD STATUS")
-		log.Info(fmt.Sprintf("  Phase: %s | Restarts: %d | Ready: %s",
-			pod.Status.Phase,
-			container.RestartCount,
-			getReadyCount(pod)))
-		log.Info("")
-
-		// Events Section (only show once for first container)
-		if i == 0 && len(events) > 0 {
-			log.Info("⚠️  CRITICAL EVENTS (Warning/Error only - Last 10)")
-			count := 0
-			for _, event := range events {
-				if count >= 10 {
-					break
-				}
-				ago := time.Since(event.Timestamp).Round(time.Second)
-				eventLine := fmt.Sprintf("  %s ago | %-7s | %-15s | %s",
-					formatDuration(ago),
-					event.Type,
-					event.Reason,
-					event.Message)
-
-				if event.Type == "Error" || strings.Contains(event.Reason, "Failed") {
-					log.ErrorMsg(eventLine)
-				} else {
-					log.Info(eventLine)
-				}
-				count++
-			}
-			log.Info("")
-		}
-
-		// Logs Section
-		if i < len(logResults) {
-			logResult := logResults[i]
-
-			// Previous logs
-			if logResult.PreviousError == nil && logResult.Previous != "" {
-				log.Info("🔥 PREVIOUS LOGS (Last Crash) - Last 50 lines")
-				log.Info(strings.Repeat("-", 80))
-				printHighlightedLogs(logResult.Previous, opts.NoColor)
-				log.Info(strings.Repeat("-", 80))
-				log.Info("")
-			} else if logResult.PreviousError != nil && !strings.Contains(logResult.PreviousError.Error(), "previous terminated container") {
-				log.Info("🔥 PREVIOUS LOGS")
-				log.Info(fmt.Sprintf("  (No previous logs: %v)", logResult.PreviousError))
-				log.Info("")
-			}
-
-			// Current logs
-			if logResult.CurrentError == nil && logResult.Current != "" {
-				log.Info("🔄 CURRENT LOGS - Last 50 lines")
-				log.Info(strings.Repeat("-", 80))
-				printHighlightedLogs(logResult.Current, opts.NoColor)
-				log.Info(strings.Repeat("-", 80))
-				log.Info("")
-			} else if logResult.CurrentError != nil {
-				log.Info("🔄 CURRENT LOGS")
-				log.Info(fmt.Sprintf("  (No current logs: %v)", logResult.CurrentError))
-				log.Info("")
-			}
-		}
+// logWindow describes which part of a log a section shows
+func (o *TriageOptions) logWindow() string {
+	if o.Grep != "" {
+		return fmt.Sprintf("Lines matching %q", o.Grep)
 	}
-
-	// Healthy containers summary
-	if len(healthyContainers) > 0 {
-		log.Info(strings.Repeat("-", 80))
-		var healthyNames []string
-		for _, c := range healthyContainers {
-			healthyNames = append(healthyNames, fmt.Sprintf("%s (%s, %d restarts)", c.Name, c.State, c.RestartCount))
-		}
-		log.Info(fmt.Sprintf("ℹ️  %d other container(s) running normally: [%s]",
-			len(healthyContainers),
-			strings.Join(healthyNames, ", ")))
-		log.Info("")
+	if o.Lines <= 0 {
+		return "All lines"
 	}
+	return fmt.Sprintf("Last %d lines", o.Lines)
 }
 
-// printHighlightedLogs outputs logs with keyword highlighting
-func printHighlightedLogs(logs string, noColor bool) {
-	lines := strings.Split(logs, "\n")
-	keywords := []string{"ERROR", "error", "Error", "panic", "PANIC", "Panic",
-		"fatal", "FATAL", "Fatal", "exception", "Exception", "EXCEPTION",
-		"failed", "Failed", "FAILED", "killed", "Killed", "KILLED", "OOMKilled"}
+// highlightKeywords mark plain-text lines as errors
+var highlightKeywords = []string{"ERROR", "error", "Error", "panic", "PANIC", "Panic",
+	"fatal", "FATAL", "Fatal", "exception", "Exception", "EXCEPTION",
+	"failed", "Failed", "FAILED", "killed", "Killed", "KILLED", "OOMKilled"}
 
-	log := logger.NewLogger()
-	for _, line := range lines {
-		if line == "" {
+// LogLine is a log line as shown in a log section
+type LogLine struct {
+	// Text is the line as shown, e.g. a structured line in compact form with
+	// "[xN] " in front when N near-identical lines were folded into it
+	Text string
+	// Number counts lines from the start of the log; only set by --grep
+	Number int
+	// Count is how many near-identical lines were folded into this one
+	Count int
+	// Level is the structured log level, or the severity of the matching highlight rule
+	Level string
+	// Color is the color of the matching highlight rule
+	Color string
+	// Highlight marks errors: error levels, error keywords and --grep matches
+	Highlight bool
+	// Gap marks a --grep line that doesn't follow the one before it
+	Gap bool
+}
+
+// highlightLines picks the lines of a log to show, last --lines first
+// JSON and logfmt lines are rendered compact and marked by their level;
+// lines below --min-level are dropped and counted in hidden
+func highlightLines(logs string, opts *TriageOptions) (lines []LogLine, hidden int) {
+	keys := opts.logKeys()
+	for _, text := range splitLogLines(logs) {
+		line := LogLine{Text: text, Count: 1}
+		if rule := opts.Config.matchHighlight(text); rule != nil {
+			line.Level, line.Color = rule.Severity, rule.Color
+		}
+		if !opts.RawLogs {
+			if parsed, ok := parseStructuredLine(text, keys); ok {
+				// Structured lines without a recognised level fall back to keywords
+				line.Text, line.Level, line.Color = parsed.render(), parsed.Level, ""
+			}
+		}
+		if !levelAtLeast(line.Level, opts.MinLevel) {
+			hidden++
 			continue
 		}
 
-		shouldHighlight := false
-		if !noColor {
-			for _, keyword := range keywords {
-				if strings.Contains(line, keyword) {
-					shouldHighlight = true
+		switch line.Level {
+		case LevelError, LevelFatal:
+			line.Highlight = true
+		case "":
+			for _, keyword := range highlightKeywords {
+				if strings.Contains(line.Text, keyword) {
+					line.Highlight = true
 					break
 				}
 			}
 		}
+		lines = append(lines, line)
+	}
 
-		if shouldHighlight {
-			log.ErrorMsg("  " + line)
-		} else {
-			fmt.Println("  " + line)
+	// Fold runs of near-identical lines, then keep the --lines tail of what's left
+	if !opts.NoFold {
+		texts := make([]string, len(lines))
+		for i, line := range lines {
+			texts[i] = line.Text
+		}
+		last, counts := foldRuns(texts)
+		folded := make([]LogLine, len(last))
+		for i, idx := range last {
+			folded[i] = lines[idx]
+			folded[i].Count = counts[i]
+		}
+		lines = folded
+	}
+	if opts.Lines > 0 && int64(len(lines)) > opts.Lines {
+		lines = lines[len(lines)-int(opts.Lines):]
+	}
+	for i := range lines {
+		if lines[i].Count > 1 {
+			lines[i].Text = fmt.Sprintf("[x%d] %s", lines[i].Count, lines[i].Text)
+		}
+	}
+	return lines, hidden
+}
+
+// fetchLines is how many lines are fetched per log stream: more than Lines
+// when folding, so repeated lines don't push the cause out of the window,
+// and zero (the whole log) when searching
+func (o *TriageOptions) fetchLines() int64 {
+	// --grep searches the whole log
+	if o.Grep != "" {
+		return 0
+	}
+	if !o.NoFold && o.FoldScanLines > o.Lines {
+		return o.FoldScanLines
+	}
+	return o.Lines
+}
+
+// logKeys combines the structured log keys from flags, config and defaults, in that order
+func (o *TriageOptions) logKeys() LogKeys {
+	keys := o.LogKeys
+	if o.Config != nil {
+		keys = LogKeys{
+			Level:   append(append([]string{}, keys.Level...), o.Config.LogKeys.Level...),
+			Message: append(append([]string{}, keys.Message...), o.Config.LogKeys.Message...),
+			Error:   append(append([]string{}, keys.Error...), o.Config.LogKeys.Error...),
+			Time:    append(append([]string{}, keys.Time...), o.Config.LogKeys.Time...),
 		}
 	}
+	return keys.WithDefaults()
 }
 
 // formatDuration formats a duration in a human-readable way
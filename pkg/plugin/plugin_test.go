@@ -12,6 +12,7 @@ func TestIsPodTrulyHealthy(t *testing.T) {
 	tests := []struct {
 		name     string
 		pod      *corev1.Pod
+		config   *Config
 		expected bool
 	}{
 		{
@@ -127,11 +128,36 @@ func TestIsPodTrulyHealthy(t *testing.T) {
 			},
 			expected: false,
 		},
+		{
+			name:   "Multi-container - Ignored sidecar has restarts",
+			pod:    runningPod("web", corev1.PodRunning, true, running("app", 0, true), running("istio-proxy", 2, true)),
+			config: &Config{IgnoreContainers: []string{"istio-proxy"}},
+			// Only the ignored sidecar restarted
+			expected: true,
+		},
+		{
+			name:     "Multi-container - Ignored sidecar not Ready",
+			pod:      runningPod("web", corev1.PodRunning, false, running("app", 0, true), running("istio-proxy", 1, false)),
+			config:   &Config{IgnoreContainers: []string{"istio-proxy"}},
+			expected: true,
+		},
+		{
+			name:     "Multi-container - App restarted next to an ignored sidecar",
+			pod:      runningPod("web", corev1.PodRunning, true, running("app", 3, true), running("istio-proxy", 0, true)),
+			config:   &Config{IgnoreContainers: []string{"istio-proxy"}},
+			expected: false,
+		},
+		{
+			name:     "Multi-container - App not Ready next to an ignored sidecar",
+			pod:      runningPod("web", corev1.PodRunning, false, running("app", 0, false), running("istio-proxy", 0, false)),
+			config:   &Config{IgnoreContainers: []string{"istio-proxy"}},
+			expected: false,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := isPodTrulyHealthy(tt.pod)
+			result := isPodTrulyHealthy(tt.pod, tt.config)
 			if result != tt.expected {
 				t.Errorf("isPodTrulyHealthy() = %v, want %v", result, tt.expected)
 			}
@@ -347,7 +373,7 @@ func TestIdentifyFailedContainers(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			failed, healthy := identifyFailedContainers(tt.pod, tt.allContainers)
+			failed, healthy := identifyFailedContainers(tt.pod, tt.allContainers, nil)
 
 			if len(failed) != tt.expectedFailedLen {
 				t.Errorf("identifyFailedContainers() failed len = %v, want %v", len(failed), tt.expectedFailedLen)
@@ -0,0 +1,82 @@
+package plugin
+
+import (
+	"context"
+)
+
+// Defaults for the concurrency and client-side rate limit flags
+const (
+	// DefaultConcurrency is how many pods are triaged, and API requests kept
+	// in flight, at a time
+	DefaultConcurrency = 5
+	// DefaultQPS and DefaultBurst limit the API requests per second; client-go
+	// allows 5 and bursts of 10 otherwise, too few for a run over many pods
+	DefaultQPS   = 20
+	DefaultBurst = 40
+)
+
+// workerPool bounds how many API requests a triage has in flight, across
+// all the pods and containers being collected; a nil pool doesn't bound them
+type workerPool struct {
+	slots chan struct{}
+}
+
+func newWorkerPool(size int) *workerPool {
+	if size <= 0 {
+		size = DefaultConcurrency
+	}
+	return &workerPool{slots: make(chan struct{}, size)}
+}
+
+// do runs fn once a slot is free, or returns the context error if ctx ends first
+func (p *workerPool) do(ctx context.Context, fn func() error) error {
+	if p == nil {
+		return fn()
+	}
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-p.slots }()
+	return fn()
+}
+
+// forEach calls fn for each index below n on up to size workers, starting
+// them in order, and stops starting them once ctx ends. done[i] is closed
+// when index i is finished or won't be started, so results can be used in
+// order while later ones are still being collected
+func forEach(ctx context.Context, n, size int, fn func(i int)) (done []chan struct{}) {
+	if size <= 0 {
+		size = DefaultConcurrency
+	}
+	done = make([]chan struct{}, n)
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	next := make(chan int)
+	for w := 0; w < size && w < n; w++ {
+		go func() {
+			for i := range next {
+				fn(i)
+				close(done[i])
+			}
+		}()
+	}
+	go func() {
+		defer close(next)
+		for i := 0; i < n; i++ {
+			if ctx.Err() != nil {
+				close(done[i])
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				close(done[i])
+			case next <- i:
+			}
+		}
+	}()
+	return done
+}
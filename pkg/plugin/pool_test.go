@@ -0,0 +1,94 @@
+package plugin
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestWorkerPool checks that no more calls run at a time than the pool has slots
+func TestWorkerPool(t *testing.T) {
+	pool := newWorkerPool(3)
+	var mu sync.Mutex
+	running, most := 0, 0
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pool.do(context.Background(), func() error {
+				mu.Lock()
+				running++
+				if running > most {
+					most = running
+				}
+				mu.Unlock()
+				time.Sleep(5 * time.Millisecond)
+				mu.Lock()
+				running--
+				mu.Unlock()
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+
+	if most > 3 {
+		t.Errorf("%d calls ran at a time, want at most 3", most)
+	}
+}
+
+// TestWorkerPoolCancel checks that a call waiting for a slot gives up when ctx ends
+func TestWorkerPoolCancel(t *testing.T) {
+	pool := newWorkerPool(1)
+	release := make(chan struct{})
+	go pool.do(context.Background(), func() error {
+		<-release
+		return nil
+	})
+	defer close(release)
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := pool.do(ctx, func() error {
+		t.Error("call ran without a free slot")
+		return nil
+	})
+	if err != context.DeadlineExceeded {
+		t.Errorf("do() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+// TestForEach checks that every index is done once and that none are
+// started after ctx ends
+func TestForEach(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	calls := map[int]int{}
+	done := forEach(ctx, 10, 1, func(i int) {
+		mu.Lock()
+		calls[i]++
+		mu.Unlock()
+		if i == 3 {
+			cancel()
+		}
+	})
+	for i := range done {
+		<-done[i]
+	}
+
+	for i := 0; i <= 3; i++ {
+		if calls[i] != 1 {
+			t.Errorf("index %d ran %d times, want once", i, calls[i])
+		}
+	}
+	// The next one may already be on its way to the worker when ctx ends
+	if len(calls) > 5 {
+		t.Errorf("%d indexes ran, want none started well after ctx ended: %v", len(calls), calls)
+	}
+}
@@ -0,0 +1,155 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Lc-Lin/kubectl-triage/pkg/logger"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Permission is an API permission the triage uses, in a namespace
+type Permission struct {
+	Verb string
+	// Resource is a core resource, with its subresource after a slash, e.g. "pods/log"
+	Resource string
+	// Usage says what the triage does with it
+	Usage string
+}
+
+// String describes the permission as kubectl auth can-i takes it, e.g. "list events"
+func (p Permission) String() string {
+	return p.Verb + " " + p.Resource
+}
+
+var (
+	getPodsPermission    = Permission{Verb: "get", Resource: "pods", Usage: "read the triaged pod"}
+	listPodsPermission   = Permission{Verb: "list", Resource: "pods", Usage: "--selector, the pod picker and pod name prefixes"}
+	listEventsPermission = Permission{Verb: "list", Resource: "events", Usage: "the critical events section"}
+	getLogsPermission    = Permission{Verb: "get", Resource: "pods/log", Usage: "the previous and current logs"}
+)
+
+// Permissions are the namespaced permissions the triage uses, all on core resources
+var Permissions = []Permission{getPodsPermission, listPodsPermission, listEventsPermission, getLogsPermission}
+
+// ForbiddenError is a section skipped because RBAC doesn't allow the call it needs
+type ForbiddenError struct {
+	Permission Permission
+	Namespace  string
+}
+
+func (e *ForbiddenError) Error() string {
+	return fmt.Sprintf("%s: forbidden — need %s in ns %s", e.Permission.Resource, e.Permission, e.Namespace)
+}
+
+// access records which of the sections that can be skipped RBAC allows;
+// a nil error means allowed
+type access struct {
+	events error
+	logs   error
+}
+
+// checkAccess asks the API server with SelfSubjectAccessReviews whether the
+// events and logs sections are allowed, so a forbidden section is skipped
+// with a note instead of failing the triage; when a review can't be made the
+// section is tried anyway
+func checkAccess(ctx context.Context, clientset kubernetes.Interface, namespace string) access {
+	var result access
+	var wg sync.WaitGroup
+	for _, check := range []struct {
+		permission Permission
+		err        *error
+	}{
+		{listEventsPermission, &result.events},
+		{getLogsPermission, &result.logs},
+	} {
+		wg.Add(1)
+		go func(permission Permission, err *error) {
+			defer wg.Done()
+			allowed, reviewErr := reviewAccess(ctx, clientset, namespace, permission)
+			if reviewErr != nil {
+				logger.NewLogger().Verbose("Couldn't check %s in ns %s: %v", permission, namespace, reviewErr)
+				return
+			}
+			if !allowed {
+				*err = &ForbiddenError{Permission: permission, Namespace: namespace}
+			}
+		}(check.permission, check.err)
+	}
+	wg.Wait()
+	return result
+}
+
+// reviewAccess runs a SelfSubjectAccessReview for one permission
+func reviewAccess(ctx context.Context, clientset kubernetes.Interface, namespace string, permission Permission) (bool, error) {
+	resource, subresource := permission.Resource, ""
+	if i := strings.Index(resource, "/"); i >= 0 {
+		resource, subresource = resource[:i], resource[i+1:]
+	}
+
+	var review *authorizationv1.SelfSubjectAccessReview
+	err := runWithContext(ctx, func() error {
+		r, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(&authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace:   namespace,
+					Verb:        permission.Verb,
+					Resource:    resource,
+					Subresource: subresource,
+				},
+			},
+		})
+		review = r
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
+
+// forbidden turns a Forbidden API error into a ForbiddenError for the
+// permission, and returns other errors as they are
+func forbidden(err error, permission Permission, namespace string) error {
+	if apierrors.IsForbidden(err) {
+		return &ForbiddenError{Permission: permission, Namespace: namespace}
+	}
+	return err
+}
+
+// RoleYAML returns a Role with the minimal permissions the triage needs in a
+// namespace, followed by the command that binds it to a user
+func RoleYAML(name, namespace string) string {
+	// One rule per resource, verbs in the order of Permissions
+	var resources []string
+	verbs := map[string][]string{}
+	usages := map[string][]string{}
+	for _, p := range Permissions {
+		if _, ok := verbs[p.Resource]; !ok {
+			resources = append(resources, p.Resource)
+		}
+		verbs[p.Resource] = append(verbs[p.Resource], fmt.Sprintf("%q", p.Verb))
+		usages[p.Resource] = append(usages[p.Resource], p.Usage)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Minimal permissions for kubectl triage in namespace %s\n", namespace)
+	fmt.Fprintf(&b, "# Bind it with: kubectl create rolebinding %s --role=%s --user=<user> -n %s\n", name, name, namespace)
+	b.WriteString("apiVersion: rbac.authorization.k8s.io/v1\n")
+	b.WriteString("kind: Role\n")
+	b.WriteString("metadata:\n")
+	fmt.Fprintf(&b, "  name: %s\n", name)
+	fmt.Fprintf(&b, "  namespace: %s\n", namespace)
+	b.WriteString("rules:\n")
+	for _, resource := range resources {
+		fmt.Fprintf(&b, "# %s\n", strings.Join(usages[resource], "; "))
+		b.WriteString("- apiGroups: [\"\"]\n")
+		fmt.Fprintf(&b, "  resources: [%q]\n", resource)
+		fmt.Fprintf(&b, "  verbs: [%s]\n", strings.Join(verbs[resource], ", "))
+	}
+	return b.String()
+}
@@ -0,0 +1,107 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// TestRunPluginForbidden checks that sections RBAC forbids are skipped with a
+// note while the rest of the triage is still shown
+func TestRunPluginForbidden(t *testing.T) {
+	tests := []struct {
+		name string
+		// denied permissions are turned down by the access reviews
+		denied []string
+		// forbidEvents makes the events list itself fail with Forbidden,
+		// as when access reviews can't be trusted
+		forbidEvents bool
+		want         []string
+		unwanted     []string
+	}{
+		{
+			name:     "events denied",
+			denied:   []string{"list events"},
+			want:     []string{"CRITICAL EVENTS - skipped", "(events: forbidden — need list events in ns triage-test)", "ERROR: database connection failed"},
+			unwanted: []string{"Back-off restarting"},
+		},
+		{
+			name:     "logs denied",
+			denied:   []string{"get pods/log"},
+			want:     []string{"Back-off restarting", "🔥 PREVIOUS LOGS - skipped", "🔄 CURRENT LOGS - skipped", "(pods/log: forbidden — need get pods/log in ns triage-test)"},
+			unwanted: []string{"ERROR: database connection failed"},
+		},
+		{
+			name:         "events list forbidden",
+			forbidEvents: true,
+			want:         []string{"CRITICAL EVENTS - skipped", "(events: forbidden — need list events in ns triage-test)", "ERROR: database connection failed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := runningPod("crashloop-pod", corev1.PodRunning, false, waiting("app", "CrashLoopBackOff", 5))
+			event := podEvent(pod, corev1.EventTypeWarning, "BackOff", "Back-off restarting failed container", 0)
+			clientset := newFakeClientset(map[string]containerLogs{
+				"app": {Previous: "ERROR: database connection failed\n"},
+			}, pod, &event)
+			for _, permission := range tt.denied {
+				clientset.denied[permission] = true
+			}
+			if tt.forbidEvents {
+				clientset.PrependReactor("list", "events", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "events"}, "", nil)
+				})
+			}
+
+			opts := &TriageOptions{
+				PodName:   pod.Name,
+				Namespace: testNamespace,
+				Lines:     50,
+				NoColor:   true,
+				NewClient: func(*genericclioptions.ConfigFlags) (kubernetes.Interface, error) {
+					return clientset, nil
+				},
+			}
+
+			output, runErr := runPlugin(opts)
+			if code := ExitCode(runErr); code != ExitFailing {
+				t.Fatalf("RunPlugin() error = %v, exit code %d; want %d", runErr, code, ExitFailing)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(output, want) {
+					t.Errorf("output lacks %q:\n%s", want, output)
+				}
+			}
+			for _, unwanted := range tt.unwanted {
+				if strings.Contains(output, unwanted) {
+					t.Errorf("output has %q:\n%s", unwanted, output)
+				}
+			}
+		})
+	}
+}
+
+// TestRoleYAML checks the Role printed by kubectl triage rbac
+func TestRoleYAML(t *testing.T) {
+	role := RoleYAML("kubectl-triage", "prod")
+	for _, want := range []string{
+		"kind: Role\n",
+		"  name: kubectl-triage\n  namespace: prod\n",
+		"  resources: [\"pods\"]\n  verbs: [\"get\", \"list\"]\n",
+		"  resources: [\"events\"]\n  verbs: [\"list\"]\n",
+		"  resources: [\"pods/log\"]\n  verbs: [\"get\"]\n",
+		"kubectl create rolebinding kubectl-triage --role=kubectl-triage --user=<user> -n prod",
+	} {
+		if !strings.Contains(role, want) {
+			t.Errorf("RoleYAML() lacks %q:\n%s", want, role)
+		}
+	}
+}
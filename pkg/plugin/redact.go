@@ -0,0 +1,152 @@
+package plugin
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RedactRule masks text matching a regular expression
+// Text captured by the optional named groups "pre" and "post" is kept, so a
+// rule can mask a value while leaving its key visible
+type RedactRule struct {
+	Name    string `mapstructure:"name"`
+	Pattern string `mapstructure:"pattern"`
+
+	regexp *regexp.Regexp
+}
+
+// builtinRedactRules detect common secrets and PII; order matters, since
+// credentials in connection strings would otherwise be taken for emails
+var builtinRedactRules = []RedactRule{
+	{Name: "private-key", Pattern: `-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?(?:-----END [A-Z ]*PRIVATE KEY-----|$)`},
+	{Name: "connection-password", Pattern: `(?P<pre>\b[a-zA-Z][a-zA-Z0-9+.-]*://[^:/\s@]+:)[^@\s/]+(?P<post>@)`},
+	{Name: "bearer-token", Pattern: `(?P<pre>(?i)\bbearer\s+)[A-Za-z0-9\-._~+/]+=*`},
+	{Name: "jwt", Pattern: `\beyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]+`},
+	{Name: "aws-access-key", Pattern: `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`},
+	{Name: "aws-secret-key", Pattern: `(?P<pre>(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?)[A-Za-z0-9/+=]{40}`},
+	{Name: "github-token", Pattern: `\bgh[pousr]_[A-Za-z0-9]{36,}\b`},
+	{Name: "slack-token", Pattern: `\bxox[abprs]-[A-Za-z0-9-]{10,}`},
+	{Name: "secret-assignment", Pattern: `(?P<pre>(?i)\b(?:password|passwd|pwd|secret|client_secret|token|access_token|api[_-]?key|apikey)["']?\s*[:=]\s*["']?)[^\s"',;&\[][^\s"',;&]{2,}`},
+	{Name: "email", Pattern: `\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`},
+}
+
+// Redactor masks secrets and PII, counting the distinct values it masked per rule
+// A token repeated on every retry line, or shown in both the logs and the
+// crash trace, counts once
+type Redactor struct {
+	rules  []RedactRule
+	counts map[string]int
+	seen   map[string]bool
+}
+
+// NewRedactor builds a redactor from the built-in detectors followed by the given rules
+func NewRedactor(extra []RedactRule) (*Redactor, error) {
+	rules := make([]RedactRule, 0, len(builtinRedactRules)+len(extra))
+	rules = append(rules, builtinRedactRules...)
+	rules = append(rules, extra...)
+
+	for i := range rules {
+		if err := rules[i].compile(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Redactor{rules: rules, counts: map[string]int{}, seen: map[string]bool{}}, nil
+}
+
+func (r *RedactRule) compile() error {
+	if r.regexp != nil {
+		return nil
+	}
+	re, err := regexp.Compile(r.Pattern)
+	if err != nil {
+		return fmt.Errorf("redact pattern %q: %v", r.Pattern, err)
+	}
+	if r.Name == "" {
+		r.Name = "custom"
+	}
+	r.regexp = re
+	return nil
+}
+
+// Redact masks every match in text as [REDACTED:<rule>]
+func (r *Redactor) Redact(text string) string {
+	for _, rule := range r.rules {
+		matches := rule.regexp.FindAllString(text, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		for _, match := range matches {
+			key := rule.Name + "\x00" + match
+			if !r.seen[key] {
+				r.seen[key] = true
+				r.counts[rule.Name]++
+			}
+		}
+		text = rule.regexp.ReplaceAllString(text, "${pre}[REDACTED:"+rule.Name+"]${post}")
+	}
+	return text
+}
+
+// Total returns how many distinct items were masked so far
+func (r *Redactor) Total() int {
+	total := 0
+	for _, count := range r.counts {
+		total += count
+	}
+	return total
+}
+
+// Summary lists the masked items per rule, e.g. "2 bearer-token, 1 email"
+func (r *Redactor) Summary() string {
+	names := make([]string, 0, len(r.counts))
+	for name := range r.counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%d %s", r.counts[name], name))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// RedactsByDefault reports whether the output is redacted when --redact isn't
+// given: reports meant for sharing, like markdown, slack, html, templates and
+// columns fed to other tools, and JUnit files uploaded by CI, are
+func RedactsByDefault(output, junitFile string) bool {
+	if junitFile != "" {
+		return true
+	}
+	switch output {
+	case OutputMarkdown, OutputSlack, OutputHTML:
+		return true
+	}
+	// go-template= and go-template-file= reach the logs and events as well
+	return strings.HasPrefix(output, OutputGoTemplate) || strings.HasPrefix(output, OutputCustomColumns)
+}
+
+// redactResults masks event messages, logs and crash traces in place
+func redactResults(r *Redactor, events []EventInfo, logResults []LogResult) {
+	for i := range events {
+		events[i].Message = r.Redact(events[i].Message)
+	}
+
+	for i := range logResults {
+		result := &logResults[i]
+		result.Previous = r.Redact(result.Previous)
+		result.Current = r.Redact(result.Current)
+		if result.Crash != nil {
+			// Redact the trace as one block so multi-line secrets are caught
+			lines := strings.Split(r.Redact(strings.Join(result.Crash.Lines, "\n")), "\n")
+			if len(lines) != len(result.Crash.Lines) {
+				// A multi-line match collapsed lines, so the frame index no longer applies
+				result.Crash.AppFrame = -1
+			}
+			result.Crash.Lines = lines
+		}
+	}
+}
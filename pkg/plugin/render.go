@@ -0,0 +1,514 @@
+package plugin
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Lc-Lin/kubectl-triage/pkg/logger"
+)
+
+// Renderer writes a triage report in one output format
+type Renderer interface {
+	Render(w io.Writer, report *Report) error
+}
+
+// RendererFunc adapts a function to the Renderer interface
+type RendererFunc func(w io.Writer, report *Report) error
+
+// Render calls f(w, report)
+func (f RendererFunc) Render(w io.Writer, report *Report) error {
+	return f(w, report)
+}
+
+// newRenderer returns the renderer for opts.Output; templates and columns
+// are parsed here, so mistakes in them show up before anything is collected
+func newRenderer(opts *TriageOptions) (Renderer, error) {
+	output := opts.Output
+	format, arg := output, ""
+	if i := strings.Index(output, "="); i >= 0 {
+		format, arg = output[:i], output[i+1:]
+	}
+
+	switch format {
+	case "", OutputText:
+		return &TextRenderer{
+			Color:      !opts.NoColor,
+			PodHeaders: opts.Selector != "" || len(opts.PodNames) > 0,
+			Width:      opts.Width,
+			ASCII:      opts.ASCII,
+			Compact:    opts.Compact,
+		}, nil
+	case OutputMarkdown:
+		return RendererFunc(renderMarkdown), nil
+	case OutputSlack:
+		return RendererFunc(renderSlack), nil
+	case OutputHTML:
+		return RendererFunc(renderHTML), nil
+	case OutputGoTemplate:
+		return goTemplateRenderer(arg)
+	case OutputGoTemplateFile:
+		if arg == "" {
+			return nil, fmt.Errorf("-o go-template-file needs a file, e.g. go-template-file=summary.tmpl")
+		}
+		data, err := os.ReadFile(arg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template: %w", err)
+		}
+		return goTemplateRenderer(string(data))
+	case OutputCustomColumns:
+		return customColumnsRenderer(arg)
+	}
+	return nil, fmt.Errorf("unknown -o %q (use text, markdown, slack, html, go-template=, go-template-file= or custom-columns=)", output)
+}
+
+// Styles of the text output, named as logger colors
+const (
+	styleInfo  = "cyan"
+	styleError = "red"
+	styleWarn  = "yellow"
+)
+
+// Layout of the text output
+const (
+	// ruleWidth is the width of separators, unless the terminal is narrower
+	ruleWidth = 80
+	// minWrapWidth is the narrowest column event messages are wrapped into;
+	// below it they are left to the terminal
+	minWrapWidth = 20
+
+	// compactEvents, compactCrashLines and compactLogLines are the most
+	// events, crash lines and log lines per section of the compact layout
+	compactEvents     = 3
+	compactCrashLines = 5
+	compactLogLines   = 8
+)
+
+// asciiSymbols replaces the emoji and other symbols of the text output in
+// --ascii mode; emoji headers are dropped, as the titles stand on their own
+var asciiSymbols = strings.NewReplacer(
+	"📦 ", "", "⏱️  ", "", "✅ ", "", "❌ ", "", "🚨 ", "", "🔍 ", "", "📋 ", "",
+	"⚠️  ", "", "💥 ", "", "🔥 ", "", "🔄 ", "", "ℹ️  ", "", "🛠️  ", "", "🔒 ", "",
+	"•", "*", "➜", ">", "…", "...", "—", "-",
+)
+
+// TextRenderer writes the terminal output: a section per triaged container
+// with its events, crash and logs, then the next steps
+// RunPlugin renders each pod as soon as it is triaged, so text output
+// streams during a --selector run
+type TextRenderer struct {
+	// Color highlights headers, errors and warnings with ANSI colors,
+	// unless color.NoColor is set
+	Color bool
+	// PodHeaders puts a banner above each pod, to tell the pods of a run apart
+	PodHeaders bool
+	// Width is the terminal width: separators shrink to fit it, and event
+	// messages wrap in their column; zero means unknown
+	Width int
+	// ASCII leaves out emoji and other symbols that some terminals and log
+	// viewers show as boxes
+	ASCII bool
+	// Compact drops separators and blank lines and shows only the first
+	// events, the crash and the last log lines, cut to Width, so a failed
+	// container fits in about 25 lines
+	Compact bool
+}
+
+// Render writes the report as text
+func (r *TextRenderer) Render(w io.Writer, report *Report) error {
+	t := &textWriter{w: w, color: r.Color, width: r.Width, ascii: r.ASCII}
+	for _, pod := range report.Pods {
+		switch {
+		case r.Compact:
+			if r.PodHeaders {
+				t.line(styleInfo, "")
+				t.line(styleInfo, fmt.Sprintf("📦 POD %s/%s", pod.Namespace, pod.Name))
+			}
+			t.compactPod(pod)
+		default:
+			if r.PodHeaders {
+				t.line(styleInfo, "")
+				t.rule("#")
+				t.line(styleInfo, fmt.Sprintf("📦 POD %s/%s", pod.Namespace, pod.Name))
+				t.rule("#")
+			}
+			t.pod(pod)
+		}
+	}
+	if report.Partial != "" {
+		partial := fmt.Sprintf("⏱️  PARTIAL RESULTS: %s before collection finished; sections marked incomplete were cut short.", report.Partial)
+		if r.Compact {
+			partial = t.fit(partial)
+		}
+		t.line(styleError, partial)
+	}
+	return t.err
+}
+
+// textWriter writes lines of text output, keeping the first write error
+type textWriter struct {
+	w     io.Writer
+	color bool
+	width int
+	ascii bool
+	err   error
+}
+
+// line writes text in a style, or plain when style is empty or color is off
+func (t *textWriter) line(style, text string) {
+	if t.err != nil {
+		return
+	}
+	if !t.color || text == "" {
+		style = ""
+	}
+	if t.ascii {
+		text = asciiSymbols.Replace(text)
+	}
+	_, t.err = logger.Fprintln(t.w, style, text)
+}
+
+// rule writes a separator line of char, as wide as the terminal allows
+func (t *textWriter) rule(char string) {
+	width := ruleWidth
+	if t.width > 0 && t.width < width {
+		width = t.width
+	}
+	t.line(styleInfo, strings.Repeat(char, width))
+}
+
+// fit cuts text to the terminal width, when it is known
+func (t *textWriter) fit(text string) string {
+	marker := "…"
+	if t.ascii {
+		text, marker = asciiSymbols.Replace(text), "..."
+	}
+	limit := t.width - len([]rune(marker))
+	if limit <= 0 || columns(text) <= t.width {
+		return text
+	}
+
+	width, prev := 0, 0
+	for i, r := range text {
+		prev = runeColumns(r, prev)
+		width += prev
+		if width > limit {
+			return text[:i] + marker
+		}
+	}
+	return text
+}
+
+// columns estimates how many terminal columns text takes
+func columns(text string) int {
+	n, prev := 0, 0
+	for _, r := range text {
+		prev = runeColumns(r, prev)
+		n += prev
+	}
+	return n
+}
+
+// wideSymbols are the symbols below the emoji planes that terminals show two
+// columns wide on their own, e.g. ✅ and ❌
+var wideSymbols = [][2]rune{
+	{0x231A, 0x231B}, {0x23E9, 0x23EC}, {0x23F0, 0x23F0}, {0x23F3, 0x23F3},
+	{0x25FD, 0x25FE}, {0x2614, 0x2615}, {0x2648, 0x2653}, {0x267F, 0x267F},
+	{0x2693, 0x2693}, {0x26A1, 0x26A1}, {0x26AA, 0x26AB}, {0x26BD, 0x26BE},
+	{0x26C4, 0x26C5}, {0x26CE, 0x26CE}, {0x26D4, 0x26D4}, {0x26EA, 0x26EA},
+	{0x26F2, 0x26F3}, {0x26F5, 0x26F5}, {0x26FA, 0x26FA}, {0x26FD, 0x26FD},
+	{0x2705, 0x2705}, {0x270A, 0x270B}, {0x2728, 0x2728}, {0x274C, 0x274C},
+	{0x274E, 0x274E}, {0x2753, 0x2755}, {0x2757, 0x2757}, {0x2795, 0x2797},
+	{0x27B0, 0x27B0}, {0x27BF, 0x27BF}, {0x2B1B, 0x2B1C}, {0x2B50, 0x2B50},
+	{0x2B55, 0x2B55},
+}
+
+// runeColumns estimates the terminal columns of a character given those of
+// the one before it: two for emoji. The variation selector that turns a
+// symbol such as ⚠ or ⏱ into an emoji widens it to two columns, so it takes
+// one column after a narrow symbol and none after a wide one
+func runeColumns(r rune, prev int) int {
+	switch {
+	case r == '\uFE0F':
+		if prev == 1 {
+			return 1
+		}
+		return 0
+	case r >= 0x1F300:
+		return 2
+	}
+	for _, symbols := range wideSymbols {
+		if r >= symbols[0] && r <= symbols[1] {
+			return 2
+		}
+	}
+	return 1
+}
+
+func (t *textWriter) pod(pod PodReport) {
+	if pod.Status == StatusError {
+		t.line(styleError, fmt.Sprintf("❌ Couldn't triage pod '%s': %s", pod.Name, pod.Message))
+		return
+	}
+	if pod.Skipped {
+		t.line(styleInfo, fmt.Sprintf("✅ Pod '%s' is healthy (Ready %s, 0 restarts).", pod.Name, pod.Ready))
+		t.line(styleInfo, "Use --force to inspect anyway.")
+		return
+	}
+
+	for i, container := range pod.triagedContainers() {
+		t.line(styleInfo, "")
+		t.rule("=")
+		if container.Failed {
+			t.line(styleError, fmt.Sprintf("🚨 TRIAGE FOR FAILED CONTAINER: '%s' (Reason: %s)", container.Name, container.Reason))
+		} else {
+			t.line(styleInfo, fmt.Sprintf("🔍 TRIAGE FOR CONTAINER: '%s'", container.Name))
+		}
+		t.rule("=")
+		t.line(styleInfo, "")
+
+		t.line(styleInfo, "📋 POD STATUS")
+		t.line(styleInfo, fmt.Sprintf("  Phase: %s | Restarts: %d | Ready: %s", pod.Phase, container.RestartCount, pod.Ready))
+		t.line(styleInfo, "")
+
+		// Events are shown once, with the first container
+		if i == 0 {
+			t.events(pod)
+		}
+		if container.Crash != nil {
+			t.crash(container)
+		}
+		t.logs("🔥 PREVIOUS LOGS (Last Crash)", "🔥 PREVIOUS LOGS", "No previous logs", container.Previous)
+		t.logs("🔄 CURRENT LOGS", "🔄 CURRENT LOGS", "No current logs", container.Current)
+	}
+
+	if others := pod.otherContainers(); len(others) > 0 {
+		t.rule("-")
+		t.line(styleInfo, fmt.Sprintf("ℹ️  %d other container(s) running normally: [%s]", len(others), strings.Join(others, ", ")))
+		t.line(styleInfo, "")
+	}
+
+	if len(pod.NextSteps) > 0 {
+		t.line(styleInfo, "🛠️  NEXT STEPS")
+		for _, step := range pod.NextSteps {
+			t.line(styleInfo, "  • "+step.Title)
+			for _, command := range step.Commands {
+				t.line(styleInfo, "      "+command)
+			}
+		}
+		t.line(styleInfo, "")
+	}
+
+	if pod.Redact {
+		if pod.Redacted > 0 {
+			t.line(styleInfo, fmt.Sprintf("🔒 Redacted %d item(s): %s", pod.Redacted, pod.RedactedSummary))
+		} else {
+			t.line(styleInfo, "🔒 Redaction on: nothing sensitive found")
+		}
+	}
+}
+
+func (t *textWriter) events(pod PodReport) {
+	if len(pod.Events) == 0 {
+		if pod.EventsForbidden {
+			t.line(styleWarn, "⚠️  CRITICAL EVENTS - skipped")
+			t.line(styleWarn, fmt.Sprintf("  (%s)", pod.EventsError))
+			t.line(styleInfo, "")
+		} else if pod.EventsError != "" {
+			t.line(styleInfo, "⚠️  CRITICAL EVENTS - incomplete")
+			t.line(styleInfo, fmt.Sprintf("  (Events not fetched: %s)", pod.EventsError))
+			t.line(styleInfo, "")
+		}
+		return
+	}
+
+	t.line(styleInfo, fmt.Sprintf("⚠️  CRITICAL EVENTS (Warning/Error only - Last %d)", maxEvents))
+	events := pod.Events
+	if len(events) > maxEvents {
+		events = events[:maxEvents]
+	}
+	for i, columns := range eventColumns(events) {
+		for _, text := range wrapColumn(columns, events[i].Message, t.width) {
+			t.line(eventStyle(events[i]), text)
+		}
+	}
+	t.line(styleInfo, "")
+}
+
+// eventColumns lays out the age, type and reason of each event in aligned
+// columns, up to where the message starts
+func eventColumns(events []EventInfo) []string {
+	ages := make([]string, len(events))
+	ageWidth, reasonWidth := 0, 15
+	for i, event := range events {
+		ages[i] = formatDuration(time.Since(event.Timestamp).Round(time.Second)) + " ago"
+		if len(ages[i]) > ageWidth {
+			ageWidth = len(ages[i])
+		}
+		if len(event.Reason) > reasonWidth {
+			reasonWidth = len(event.Reason)
+		}
+	}
+
+	columns := make([]string, len(events))
+	for i, event := range events {
+		columns[i] = fmt.Sprintf("  %-*s | %-7s | %-*s | ", ageWidth, ages[i], event.Type, reasonWidth, event.Reason)
+	}
+	return columns
+}
+
+// eventStyle highlights errors and failures
+func eventStyle(event EventInfo) string {
+	if event.Type == "Error" || strings.Contains(event.Reason, "Failed") {
+		return styleError
+	}
+	return styleInfo
+}
+
+// wrapColumn writes text after prefix, wrapped to width with the lines after
+// the first indented to start under it; without a width, or when the column
+// left is too narrow, the text stays on one line
+func wrapColumn(prefix, text string, width int) []string {
+	indent := columns(prefix)
+	if width-indent < minWrapWidth || indent+columns(text) <= width {
+		return []string{prefix + text}
+	}
+
+	wrapped := wrapText(text, width-indent)
+	lines := []string{prefix + wrapped[0]}
+	for _, line := range wrapped[1:] {
+		lines = append(lines, strings.Repeat(" ", indent)+line)
+	}
+	return lines
+}
+
+// wrapText breaks text into lines of at most width characters at spaces,
+// splitting words longer than a line
+func wrapText(text string, width int) []string {
+	var lines []string
+	var line []rune
+	for _, field := range strings.Fields(text) {
+		word := []rune(field)
+		for len(word) > width {
+			if len(line) > 0 {
+				lines = append(lines, string(line))
+				line = nil
+			}
+			lines = append(lines, string(word[:width]))
+			word = word[width:]
+		}
+		switch {
+		case len(word) == 0:
+		case len(line) == 0:
+			line = word
+		case len(line)+1+len(word) <= width:
+			line = append(append(line, ' '), word...)
+		default:
+			lines = append(lines, string(line))
+			line = word
+		}
+	}
+	if len(line) > 0 || len(lines) == 0 {
+		lines = append(lines, string(line))
+	}
+	return lines
+}
+
+// crash writes an extracted stack trace with the first application frame marked
+func (t *textWriter) crash(container ContainerReport) {
+	trace := container.Crash
+	t.line(styleError, fmt.Sprintf("💥 CRASH (%s)", trace.Language))
+	t.rule("-")
+
+	start, end := trace.window()
+	if start > 0 {
+		t.line(styleInfo, fmt.Sprintf("  ... (%d earlier trace line(s) not shown)", start))
+	}
+	for _, line := range container.CrashLines {
+		t.line(lineStyle(line), line.Text)
+	}
+	if end < len(trace.Lines) {
+		t.line(styleInfo, fmt.Sprintf("  ... (%d more trace line(s) not shown)", len(trace.Lines)-end))
+	}
+	if trace.OtherGoroutines > 0 {
+		t.line(styleInfo, fmt.Sprintf("  ... (%d other goroutine(s) not shown)", trace.OtherGoroutines))
+	}
+	t.rule("-")
+	t.line(styleInfo, "")
+}
+
+// logs writes a log section: the shown lines under title, whatever arrived
+// before a fetch was cut short, or why the log couldn't be fetched
+func (t *textWriter) logs(title, errorTitle, errorText string, section LogSection) {
+	switch {
+	case section.Empty():
+		return
+	case section.Forbidden:
+		t.line(styleWarn, errorTitle+" - skipped")
+		t.line(styleWarn, fmt.Sprintf("  (%s)", section.Error))
+		t.line(styleInfo, "")
+		return
+	case section.Incomplete:
+		t.line(styleInfo, title+" - incomplete")
+		if section.Size == 0 {
+			t.line(styleInfo, fmt.Sprintf("  (Log fetch cut short before any output: %s)", section.Error))
+			t.line(styleInfo, "")
+			return
+		}
+		t.rule("-")
+		t.logLines(section)
+		t.line(styleInfo, fmt.Sprintf("  ... (log fetch cut short: %s)", section.Error))
+	case section.Error != "":
+		t.line(styleInfo, errorTitle)
+		t.line(styleInfo, fmt.Sprintf("  (%s: %s)", errorText, section.Error))
+		t.line(styleInfo, "")
+		return
+	default:
+		t.line(styleInfo, title+" - "+section.Window)
+		t.rule("-")
+		t.logLines(section)
+	}
+	t.rule("-")
+	t.line(styleInfo, "")
+}
+
+// logLines writes the lines of a section after notes on what was left out;
+// --grep lines are numbered grep-style, "12:" for a match and "11-" for context
+func (t *textWriter) logLines(section LogSection) {
+	if section.CutAtBytes > 0 {
+		more := "fetch more"
+		if section.Grep != "" {
+			more = "search more"
+		}
+		t.line(styleInfo, fmt.Sprintf("  ... (log cut at %d bytes; raise --limit-bytes to %s)", section.CutAtBytes, more))
+	}
+	if section.Grep != "" && len(section.Lines) == 0 && section.Omitted == 0 {
+		t.line(styleInfo, fmt.Sprintf("  (No lines match %q)", section.Grep))
+		return
+	}
+	if section.Hidden > 0 {
+		t.line(styleInfo, fmt.Sprintf("  ... (%d line(s) below %s hidden)", section.Hidden, section.MinLevel))
+	}
+	if section.Omitted > 0 {
+		t.line(styleInfo, fmt.Sprintf("  ... (%d earlier line(s) omitted to fit the output budget; use --full-lines to show all)", section.Omitted))
+	}
+	for _, line := range displayLines(section.Lines) {
+		t.line(lineStyle(line), "  "+line.Text)
+	}
+}
+
+// lineStyle picks the style of a log line: highlight rules first, then the
+// level, then keywords
+func lineStyle(line LogLine) string {
+	switch {
+	case line.Color != "":
+		return line.Color
+	case line.Level == LevelWarn:
+		return styleWarn
+	case line.Highlight:
+		return styleError
+	}
+	return ""
+}
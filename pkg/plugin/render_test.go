@@ -0,0 +1,265 @@
+package plugin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode"
+
+	"github.com/fatih/color"
+)
+
+// renderTestReport is a failing pod with an incomplete current log
+func renderTestReport() *Report {
+	return &Report{
+		Partial: "timed out after 30s",
+		Pods: []PodReport{{
+			Name:      "api-1",
+			Namespace: "prod",
+			Phase:     "Running",
+			Ready:     "0/2",
+			Status:    StatusFailing,
+			Containers: []ContainerReport{
+				{
+					Name:         "app",
+					Reason:       "CrashLoopBackOff",
+					RestartCount: 4,
+					Failed:       true,
+					Triaged:      true,
+					Previous: LogSection{Window: "Last 50 lines", Size: 40, Lines: []LogLine{
+						{Text: "starting", Count: 1},
+						{Text: "ERROR: database connection failed", Count: 1, Highlight: true},
+					}},
+					Current: LogSection{Window: "Last 50 lines", Error: "context deadline exceeded", Incomplete: true},
+				},
+				{Name: "sidecar", State: "Running"},
+			},
+			NextSteps: []NextStep{{Title: "Roll back", Commands: []string{"kubectl rollout undo deployment/api -n prod"}}},
+			Redact:    true,
+		}},
+	}
+}
+
+// TestTextRenderer checks the text output written to a writer
+func TestTextRenderer(t *testing.T) {
+	var out bytes.Buffer
+	renderer := &TextRenderer{PodHeaders: true}
+	if err := renderer.Render(&out, renderTestReport()); err != nil {
+		t.Fatal(err)
+	}
+	text := out.String()
+
+	for _, want := range []string{
+		"📦 POD prod/api-1\n",
+		"🚨 TRIAGE FOR FAILED CONTAINER: 'app' (Reason: CrashLoopBackOff)\n",
+		"  Phase: Running | Restarts: 4 | Ready: 0/2\n",
+		"🔥 PREVIOUS LOGS (Last Crash) - Last 50 lines\n",
+		"  starting\n  ERROR: database connection failed\n",
+		"🔄 CURRENT LOGS - incomplete\n  (Log fetch cut short before any output: context deadline exceeded)\n",
+		"ℹ️  1 other container(s) running normally: [sidecar (Running, 0 restarts)]\n",
+		"  • Roll back\n      kubectl rollout undo deployment/api -n prod\n",
+		"🔒 Redaction on: nothing sensitive found\n",
+		"⏱️  PARTIAL RESULTS: timed out after 30s",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output lacks %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "\x1b[") {
+		t.Errorf("output without Color has escape codes:\n%s", text)
+	}
+}
+
+// TestTextRendererColor checks that Color highlights errors
+func TestTextRendererColor(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+
+	var out bytes.Buffer
+	renderer := &TextRenderer{Color: true}
+	if err := renderer.Render(&out, renderTestReport()); err != nil {
+		t.Fatal(err)
+	}
+	if want := "\x1b[91m  ERROR: database connection failed\n"; !strings.Contains(out.String(), want) {
+		t.Errorf("output lacks %q:\n%q", want, out.String())
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+// TestTextRendererWriteError checks that a failed write is returned
+func TestTextRendererWriteError(t *testing.T) {
+	err := (&TextRenderer{}).Render(failingWriter{}, renderTestReport())
+	if err == nil || err.Error() != "disk full" {
+		t.Errorf("Render() error = %v, want disk full", err)
+	}
+}
+
+// TestTextRendererLogWindow checks that the log headers follow --lines
+func TestTextRendererLogWindow(t *testing.T) {
+	tests := []struct {
+		name string
+		opts TriageOptions
+		want string
+	}{
+		{name: "default", opts: TriageOptions{Lines: 50}, want: "🔥 PREVIOUS LOGS (Last Crash) - Last 50 lines\n"},
+		{name: "more lines", opts: TriageOptions{Lines: 100}, want: "🔥 PREVIOUS LOGS (Last Crash) - Last 100 lines\n"},
+		{name: "more lines folded", opts: TriageOptions{Lines: 100, FoldScanLines: 500}, want: "🔥 PREVIOUS LOGS (Last Crash) - Last 100 lines\n"},
+		{name: "whole log", opts: TriageOptions{Lines: -1}, want: "🔥 PREVIOUS LOGS (Last Crash) - All lines\n"},
+		{name: "grep", opts: TriageOptions{Lines: 100, Grep: "ERROR"}, want: "🔥 PREVIOUS LOGS (Last Crash) - Lines matching \"ERROR\"\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := renderTestReport()
+			app := &report.Pods[0].Containers[0]
+			app.Previous = logSection("starting\nERROR: database connection failed\n", nil, &tt.opts, newLogLimits(&tt.opts))
+
+			var out bytes.Buffer
+			if err := (&TextRenderer{}).Render(&out, report); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("output lacks %q:\n%s", tt.want, out.String())
+			}
+		})
+	}
+}
+
+// TestColumns checks the terminal width estimated for symbols and emoji
+func TestColumns(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"abc", 3},
+		{"✅ ok", 5},
+		{"❌", 2},
+		{"⚠️  x", 5},
+		{"⏱️ ", 3},
+		{"⚠ bare", 6},
+		{"🛠️  x", 5},
+		{"🔥 •", 4},
+	}
+
+	for _, tt := range tests {
+		if got := columns(tt.text); got != tt.want {
+			t.Errorf("columns(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+// compactTestReport is a failing pod with many events, a long crash and a full log
+func compactTestReport() *Report {
+	report := renderTestReport()
+	pod := &report.Pods[0]
+	for i := 0; i < 10; i++ {
+		pod.Events = append(pod.Events, EventInfo{
+			Type:      "Warning",
+			Reason:    "BackOff",
+			Message:   "Back-off restarting failed container app in pod api-1 " + strings.Repeat("and more ", 10),
+			Timestamp: time.Now().Add(-time.Duration(i) * time.Minute),
+		})
+	}
+
+	trace := &StackTrace{Language: "Go panic", AppFrame: 12}
+	for i := 0; i < 20; i++ {
+		trace.Lines = append(trace.Lines, fmt.Sprintf("main.frame%d()", i))
+	}
+	app := &pod.Containers[0]
+	app.Crash = trace
+	app.CrashLines = crashLines(trace, 0)
+	app.Previous.Lines = nil
+	for i := 0; i < 50; i++ {
+		app.Previous.Lines = append(app.Previous.Lines, LogLine{Text: fmt.Sprintf("line %d %s", i, strings.Repeat("x", 100)), Count: 1})
+	}
+	return report
+}
+
+// TestTextRendererCompact checks that the compact layout of a failed
+// container fits about 25 lines of the terminal width
+func TestTextRendererCompact(t *testing.T) {
+	var out bytes.Buffer
+	renderer := &TextRenderer{Width: 80, Compact: true}
+	if err := renderer.Render(&out, compactTestReport()); err != nil {
+		t.Fatal(err)
+	}
+	text := out.String()
+
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if len(lines) > 27 {
+		t.Errorf("compact output has %d lines, want about 25:\n%s", len(lines), text)
+	}
+	for _, line := range lines {
+		if columns(line) > 80 {
+			t.Errorf("line is wider than 80 columns: %q", line)
+		}
+	}
+	for _, want := range []string{
+		"🚨 FAILED 'app': CrashLoopBackOff | Phase: Running | Restarts: 4 | Ready: 0/2\n",
+		"⚠️  EVENTS (3 of 10)\n",
+		"  main.frame0()\n  main.frame11()\n➜ main.frame12()\n",
+		"🔥 PREVIOUS LOGS - last 8 line(s)\n  line 42 ",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output lacks %q:\n%s", want, text)
+		}
+	}
+}
+
+// TestTextRendererASCII checks that --ascii output has no emoji or other symbols
+func TestTextRendererASCII(t *testing.T) {
+	for _, compact := range []bool{false, true} {
+		report := compactTestReport()
+		report.Pods[0].EventsError = (&ForbiddenError{Permission: listEventsPermission, Namespace: "prod"}).Error()
+
+		var out bytes.Buffer
+		renderer := &TextRenderer{Width: 60, ASCII: true, Compact: compact, PodHeaders: true}
+		if err := renderer.Render(&out, report); err != nil {
+			t.Fatal(err)
+		}
+		for i, r := range out.String() {
+			if r > unicode.MaxASCII {
+				t.Errorf("compact=%v: output has %q at %d:\n%s", compact, r, i, out.String())
+				break
+			}
+		}
+		if want := "TRIAGE FOR FAILED CONTAINER: 'app'"; !compact && !strings.Contains(out.String(), "\n"+want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+}
+
+// TestWrapColumn tests wrapping event messages under their column
+func TestWrapColumn(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		width int
+		want  []string
+	}{
+		{"no width", "pulling image busybox", 0, []string{"  5m | pulling image busybox"}},
+		{"fits", "pulling image busybox", 40, []string{"  5m | pulling image busybox"}},
+		{"wrapped", "pulling image busybox from docker.io", 28, []string{"  5m | pulling image busybox", "       from docker.io"}},
+		{"long word", "image registry.example.com/team/app", 28, []string{"  5m | image", "       registry.example.com/", "       team/app"}},
+		{"too narrow", "pulling image busybox", 20, []string{"  5m | pulling image busybox"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := wrapColumn("  5m | ", tt.text, tt.width)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wrapColumn() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
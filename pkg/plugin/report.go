@@ -0,0 +1,406 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Output formats for TriageOptions.Output
+const (
+	// OutputText is the colored terminal output
+	OutputText = "text"
+	// OutputMarkdown is GitHub/GitLab flavored markdown with collapsible log blocks
+	OutputMarkdown = "markdown"
+	// OutputSlack is Slack mrkdwn that fits in a single message
+	OutputSlack = "slack"
+	// OutputHTML is a self-contained HTML page
+	OutputHTML = "html"
+	// OutputGoTemplate runs the Go template after "go-template=" against the report
+	OutputGoTemplate = "go-template"
+	// OutputGoTemplateFile runs the Go template in the file after "go-template-file="
+	OutputGoTemplateFile = "go-template-file"
+	// OutputCustomColumns prints a table with a row per pod and the columns
+	// after "custom-columns=", e.g. "POD:.Name,STATUS:.Status"
+	OutputCustomColumns = "custom-columns"
+)
+
+// Pod statuses in a report
+const (
+	StatusHealthy  = "healthy"
+	StatusDegraded = "degraded"
+	StatusPending  = "pending"
+	StatusFailing  = "failing"
+	// StatusError is a pod that couldn't be looked up or triaged; its Message says why
+	StatusError = "error"
+)
+
+// maxEvents is how many events are shown per pod, most recent first
+const maxEvents = 10
+
+// Report is the result of a triage run, as rendered by the -o formats
+type Report struct {
+	Pods []PodReport
+	// Generated is when the report was built
+	Generated time.Time
+	// Partial is why collection was cut short, e.g. "timed out after 30s";
+	// empty when it finished
+	Partial string
+}
+
+// PodReport is the triage of one pod
+type PodReport struct {
+	Name      string
+	Namespace string
+	Phase     string
+	// Ready is the count of ready containers, e.g. "1/2"
+	Ready    string
+	Restarts int32
+	// Status is one of StatusHealthy, StatusDegraded, StatusPending or StatusFailing
+	Status string
+	// Message explains the status; empty for healthy pods
+	Message string
+	// Skipped is set for truly healthy pods, which aren't collected unless forced
+	Skipped bool
+
+	// Containers hold the triaged containers first, then the others
+	Containers  []ContainerReport
+	Events      []EventInfo
+	EventsError string
+	// EventsForbidden is set when RBAC doesn't allow listing events
+	EventsForbidden bool
+	NextSteps       []NextStep
+	// Timeline lists what happened to the pod, oldest first
+	Timeline []TimelineEntry
+
+	// Redact is set when --redact was on; Redacted counts the distinct values
+	// it masked, described by RedactedSummary
+	Redact          bool
+	Redacted        int
+	RedactedSummary string
+}
+
+// ContainerReport is the state and logs of one container
+type ContainerReport struct {
+	Name         string
+	State        string
+	Reason       string
+	RestartCount int32
+	Failed       bool
+	Ignored      bool
+	// Triaged containers had their logs collected; the others are only summarised
+	Triaged bool
+
+	// Crash is the last stack trace found in the previous logs; CrashLines
+	// are the lines of it to show
+	Crash      *StackTrace
+	CrashLines []LogLine
+	Previous   LogSection
+	Current    LogSection
+}
+
+// Status is the reason the container is in its state, or the state itself
+func (c ContainerReport) Status() string {
+	if c.Reason != "" {
+		return c.Reason
+	}
+	return c.State
+}
+
+// Kinds of timeline entries
+const (
+	TimelinePod       = "pod"
+	TimelineContainer = "container"
+	TimelineEvent     = "event"
+)
+
+// TimelineEntry is one moment in the life of a pod
+type TimelineEntry struct {
+	Time time.Time
+	// Kind is TimelinePod, TimelineContainer or TimelineEvent
+	Kind string
+	Text string
+	// Level is LevelError or LevelWarn for entries that point at the failure
+	Level string
+}
+
+// LogSection is the part of a log stream shown in a report
+type LogSection struct {
+	// Window describes which lines are shown, e.g. "Last 50 lines"
+	Window string
+	Lines  []LogLine
+	// Size is the length of the fetched log in bytes
+	Size int
+	// Grep is the --grep pattern the lines match; MinLevel the --min-level
+	// below which lines were hidden
+	Grep     string
+	MinLevel string
+	// Error explains why the log couldn't be fetched, or why the fetch was cut short
+	Error string
+	// Incomplete marks a fetch cut short by the timeout or Ctrl-C
+	Incomplete bool
+	// Forbidden marks a log RBAC doesn't allow reading
+	Forbidden bool
+	// Hidden counts lines below --min-level; Omitted the oldest lines left out
+	// to fit the output budget
+	Hidden  int
+	Omitted int
+	// CutAtBytes is set when the fetch stopped at --limit-bytes
+	CutAtBytes int64
+}
+
+// Empty reports whether the section has nothing to show
+func (s LogSection) Empty() bool {
+	return len(s.Lines) == 0 && s.Size == 0 && s.Error == ""
+}
+
+// buildReport turns the collected triage results into a report
+func buildReport(results []*podTriage, opts *TriageOptions, kubectl kubectlCommand, partial string) *Report {
+	report := &Report{Partial: partial, Generated: time.Now()}
+	for _, result := range results {
+		report.Pods = append(report.Pods, podReport(result, opts, kubectl))
+	}
+	return report
+}
+
+func podReport(result *podTriage, opts *TriageOptions, kubectl kubectlCommand) PodReport {
+	// The output budget is per pod, so every pod of a --selector run gets its logs
+	limits := newLogLimits(opts)
+	pod := result.Pod
+	if result.Err != nil {
+		return PodReport{Name: pod.Name, Namespace: pod.Namespace, Status: StatusError, Message: result.Err.Error()}
+	}
+	report := PodReport{
+		Name:      pod.Name,
+		Namespace: pod.Namespace,
+		Phase:     string(pod.Status.Phase),
+		Ready:     getReadyCount(pod),
+		Status:    StatusHealthy,
+		Skipped:   result.Skipped,
+		Events:    result.Events,
+		NextSteps: nextSteps(pod, result.Failed, kubectl),
+		Timeline:  podTimeline(pod, result.Events),
+	}
+	for _, cs := range pod.Status.ContainerStatuses {
+		report.Restarts += cs.RestartCount
+	}
+	if result.Outcome != nil {
+		report.Status = outcomeStatus(result.Outcome)
+		report.Message = result.Outcome.Message
+	}
+	if result.EventsErr != nil {
+		var forbiddenErr *ForbiddenError
+		report.EventsError = result.EventsErr.Error()
+		report.EventsForbidden = errors.As(result.EventsErr, &forbiddenErr)
+	}
+	if result.Redactor != nil {
+		report.Redact = true
+		report.Redacted = result.Redactor.Total()
+		report.RedactedSummary = result.Redactor.Summary()
+	}
+
+	others := result.Healthy
+	if result.Skipped {
+		others, _ = identifyFailedContainers(pod, true, opts.Config)
+	}
+	for i, container := range result.Failed {
+		containerReport := newContainerReport(container)
+		containerReport.Triaged = true
+		if i < len(result.Logs) {
+			logs := result.Logs[i]
+			if logs.Crash != nil {
+				containerReport.Crash = logs.Crash
+				containerReport.CrashLines = crashLines(logs.Crash, limits.maxLineWidth)
+			}
+			containerReport.Previous = logSection(logs.Previous, logs.PreviousError, opts, limits)
+			containerReport.Current = logSection(logs.Current, logs.CurrentError, opts, limits)
+			// A container that never restarted has no previous logs to miss
+			if logs.PreviousError != nil && strings.Contains(logs.PreviousError.Error(), "previous terminated container") {
+				containerReport.Previous.Error = ""
+			}
+		}
+		report.Containers = append(report.Containers, containerReport)
+	}
+	for _, container := range others {
+		report.Containers = append(report.Containers, newContainerReport(container))
+	}
+	return report
+}
+
+func newContainerReport(container ContainerInfo) ContainerReport {
+	return ContainerReport{
+		Name:         container.Name,
+		State:        container.State,
+		Reason:       container.Reason,
+		RestartCount: container.RestartCount,
+		Failed:       container.Failed,
+		Ignored:      container.Ignored,
+	}
+}
+
+// logSection picks the lines of a fetched log to show, as the text output does
+func logSection(logs string, err error, opts *TriageOptions, limits *logLimits) LogSection {
+	section := LogSection{Window: opts.logWindow(), Size: len(logs), Grep: opts.Grep, MinLevel: opts.MinLevel}
+	if err != nil {
+		var forbiddenErr *ForbiddenError
+		section.Error = err.Error()
+		section.Forbidden = errors.As(err, &forbiddenErr)
+		if !isContextError(err) {
+			return section
+		}
+		section.Incomplete = true
+	}
+	if limits.hitLimitBytes(logs) {
+		section.CutAtBytes = limits.limitBytes
+	}
+
+	var lines []LogLine
+	if opts.Grep != "" {
+		lines, _ = grepLogLines(logs, opts)
+	} else {
+		lines, section.Hidden = highlightLines(logs, opts)
+	}
+	section.Lines, section.Omitted = limits.fitLines(lines)
+	return section
+}
+
+// podTimeline merges the pod's creation, condition changes, container starts
+// and terminations, and its events into one timeline
+func podTimeline(pod *corev1.Pod, events []EventInfo) []TimelineEntry {
+	var timeline []TimelineEntry
+	add := func(t time.Time, kind, level, text string) {
+		if !t.IsZero() {
+			timeline = append(timeline, TimelineEntry{Time: t, Kind: kind, Level: level, Text: text})
+		}
+	}
+
+	add(pod.CreationTimestamp.Time, TimelinePod, "", "Pod created")
+	for _, condition := range pod.Status.Conditions {
+		level, text := "", fmt.Sprintf("%s=%s", condition.Type, condition.Status)
+		if condition.Status != corev1.ConditionTrue {
+			level = LevelWarn
+		}
+		if condition.Reason != "" {
+			text += ": " + condition.Reason
+		}
+		if condition.Message != "" {
+			text += " (" + condition.Message + ")"
+		}
+		add(condition.LastTransitionTime.Time, TimelinePod, level, text)
+	}
+
+	terminated := func(name string, state *corev1.ContainerStateTerminated) {
+		add(state.StartedAt.Time, TimelineContainer, "", fmt.Sprintf("Container %s started", name))
+		level := ""
+		if state.ExitCode != 0 || state.Reason == "OOMKilled" {
+			level = LevelError
+		}
+		add(state.FinishedAt.Time, TimelineContainer, level, fmt.Sprintf("Container %s terminated: %s (exit code %d)", name, state.Reason, state.ExitCode))
+	}
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.LastTerminationState.Terminated != nil {
+			terminated(cs.Name, cs.LastTerminationState.Terminated)
+		}
+		switch {
+		case cs.State.Running != nil:
+			add(cs.State.Running.StartedAt.Time, TimelineContainer, "", fmt.Sprintf("Container %s started", cs.Name))
+		case cs.State.Terminated != nil:
+			terminated(cs.Name, cs.State.Terminated)
+		}
+	}
+
+	for _, event := range events {
+		level := LevelWarn
+		if event.Type == "Error" || strings.Contains(event.Reason, "Failed") {
+			level = LevelError
+		}
+		add(event.Timestamp, TimelineEvent, level, fmt.Sprintf("%s %s: %s", event.Type, event.Reason, event.Message))
+	}
+
+	sort.SliceStable(timeline, func(i, j int) bool { return timeline[i].Time.Before(timeline[j].Time) })
+	return timeline
+}
+
+// crashLines returns the lines of a stack trace to show, the first application
+// frame marked with "➜"; the first line and that frame are highlighted, and
+// lines longer than width are cut
+func crashLines(trace *StackTrace, width int) []LogLine {
+	start, end := trace.window()
+	var lines []LogLine
+	for i := start; i < end; i++ {
+		prefix := "  "
+		if i == trace.AppFrame {
+			prefix = "➜ "
+		}
+		lines = append(lines, LogLine{
+			Text:      prefix + truncateLine(sanitizeLine(trace.Lines[i]), width),
+			Count:     1,
+			Highlight: i == 0 || i == trace.AppFrame,
+		})
+	}
+	return lines
+}
+
+// displayLines lays out log lines as the text output shows them: --grep
+// lines are numbered, with "--" between regions
+func displayLines(lines []LogLine) []LogLine {
+	var shown []LogLine
+	for i, line := range lines {
+		if line.Number == 0 {
+			shown = append(shown, line)
+			continue
+		}
+		if line.Gap && i > 0 {
+			shown = append(shown, LogLine{Text: "--", Count: 1})
+		}
+		line.Text = line.grepPrefix() + " " + line.Text
+		shown = append(shown, line)
+	}
+	return shown
+}
+
+// outcomeStatus names the status of a pod outcome
+func outcomeStatus(outcome *OutcomeError) string {
+	switch {
+	case outcome == nil:
+		return StatusHealthy
+	case outcome.Code == ExitFailing:
+		return StatusFailing
+	case outcome.Code == ExitPending:
+		return StatusPending
+	default:
+		return StatusDegraded
+	}
+}
+
+// triagedContainers returns the containers whose logs were collected
+func (p PodReport) triagedContainers() []ContainerReport {
+	var triaged []ContainerReport
+	for _, container := range p.Containers {
+		if container.Triaged {
+			triaged = append(triaged, container)
+		}
+	}
+	return triaged
+}
+
+// otherContainers describes the containers that weren't triaged, e.g.
+// "sidecar (Running, 0 restarts)"
+func (p PodReport) otherContainers() []string {
+	var others []string
+	for _, c := range p.Containers {
+		if c.Triaged {
+			continue
+		}
+		if c.Ignored {
+			others = append(others, fmt.Sprintf("%s (%s, %d restarts, ignored)", c.Name, c.State, c.RestartCount))
+			continue
+		}
+		others = append(others, fmt.Sprintf("%s (%s, %d restarts)", c.Name, c.State, c.RestartCount))
+	}
+	return others
+}
@@ -0,0 +1,47 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPodTimeline(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) metav1.Time { return metav1.NewTime(start.Add(time.Duration(minutes) * time.Minute)) }
+
+	pod := runningPod("api-1", corev1.PodRunning, false)
+	pod.CreationTimestamp = at(0)
+	pod.Status.Conditions = []corev1.PodCondition{
+		{Type: corev1.PodReady, Status: corev1.ConditionFalse, Reason: "ContainersNotReady", LastTransitionTime: at(3)},
+	}
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name: "app",
+		LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+			Reason: "OOMKilled", ExitCode: 137, StartedAt: at(1), FinishedAt: at(2),
+		}},
+		State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: at(4)}},
+	}}
+	events := []EventInfo{{Type: "Warning", Reason: "BackOff", Message: "Back-off restarting failed container", Timestamp: at(5).Time}}
+
+	want := []TimelineEntry{
+		{Time: at(0).Time, Kind: TimelinePod, Text: "Pod created"},
+		{Time: at(1).Time, Kind: TimelineContainer, Text: "Container app started"},
+		{Time: at(2).Time, Kind: TimelineContainer, Level: LevelError, Text: "Container app terminated: OOMKilled (exit code 137)"},
+		{Time: at(3).Time, Kind: TimelinePod, Level: LevelWarn, Text: "Ready=False: ContainersNotReady"},
+		{Time: at(4).Time, Kind: TimelineContainer, Text: "Container app started"},
+		{Time: at(5).Time, Kind: TimelineEvent, Level: LevelWarn, Text: "Warning BackOff: Back-off restarting failed container"},
+	}
+
+	got := podTimeline(pod, events)
+	if len(got) != len(want) {
+		t.Fatalf("podTimeline() returned %d entries, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if !got[i].Time.Equal(want[i].Time) || got[i].Kind != want[i].Kind || got[i].Level != want[i].Level || got[i].Text != want[i].Text {
+			t.Errorf("entry %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/Lc-Lin/kubectl-triage/pkg/logger"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// PodNotFoundError is returned when a pod name matches no pod, or more than
+// one, in the namespace
+type PodNotFoundError struct {
+	Name      string
+	Namespace string
+	// Candidates are the pods whose names start with, or fuzzily match, Name
+	Candidates []PodCandidate
+	// OtherNamespaces hold a pod with the name, or one starting with it
+	OtherNamespaces []string
+
+	err error
+}
+
+func (e *PodNotFoundError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.err.Error())
+
+	if len(e.Candidates) > 0 {
+		fmt.Fprintf(&b, "; %d pods in namespace %s match %q:", len(e.Candidates), e.Namespace, e.Name)
+		for _, label := range CandidateLabels(e.Candidates) {
+			b.WriteString("\n  " + label)
+		}
+	} else if len(e.OtherNamespaces) > 0 {
+		where := "namespace"
+		if len(e.OtherNamespaces) > 1 {
+			where = "namespaces"
+		}
+		fmt.Fprintf(&b, " in namespace %s, but exists in %s %s (use -n %s)",
+			e.Namespace, where, strings.Join(e.OtherNamespaces, ", "), e.OtherNamespaces[0])
+	}
+	return b.String()
+}
+
+func (e *PodNotFoundError) Unwrap() error {
+	return e.err
+}
+
+// resolvePod looks up a pod that wasn't found by its exact name: the pods
+// starting with name, or failing that fuzzily matching it, are candidates
+// The only unhealthy candidate, or the only candidate at all, is returned;
+// otherwise a PodNotFoundError lists the candidates
+func resolvePod(ctx context.Context, clientset kubernetes.Interface, namespace, name string, config *Config, notFound error) (*corev1.Pod, error) {
+	var pods *corev1.PodList
+	err := runWithContext(ctx, func() error {
+		list, err := clientset.CoreV1().Pods(namespace).List(metav1.ListOptions{})
+		pods = list
+		return err
+	})
+	if err != nil {
+		return nil, notFound
+	}
+
+	matches := matchPods(pods.Items, name)
+	var unhealthy []*corev1.Pod
+	for _, pod := range matches {
+		if pod.Status.Phase != corev1.PodSucceeded && !isPodTrulyHealthy(pod, config) {
+			unhealthy = append(unhealthy, pod)
+		}
+	}
+
+	var pod *corev1.Pod
+	switch {
+	case len(unhealthy) == 1:
+		pod = unhealthy[0]
+	case len(matches) == 1:
+		pod = matches[0]
+	}
+	if pod != nil {
+		logger.NewLogger().Warn("%s", fmt.Sprintf("🔎 Pod '%s' not found; triaging '%s'", name, pod.Name))
+		return pod, nil
+	}
+
+	notFoundErr := &PodNotFoundError{Name: name, Namespace: namespace, err: notFound}
+	for _, match := range matches {
+		notFoundErr.Candidates = append(notFoundErr.Candidates, podCandidate(match, config))
+	}
+	sortCandidates(notFoundErr.Candidates)
+	if len(matches) == 0 {
+		notFoundErr.OtherNamespaces = namespacesWithPod(ctx, clientset, namespace, name)
+	}
+	return nil, notFoundErr
+}
+
+// matchPods returns the pods whose names start with name, or if there are
+// none, those that fuzzily match it
+func matchPods(pods []corev1.Pod, name string) []*corev1.Pod {
+	var prefixed, fuzzy []*corev1.Pod
+	for i := range pods {
+		switch {
+		case strings.HasPrefix(pods[i].Name, name):
+			prefixed = append(prefixed, &pods[i])
+		case FuzzyMatch(name, pods[i].Name):
+			fuzzy = append(fuzzy, &pods[i])
+		}
+	}
+	if len(prefixed) > 0 {
+		return prefixed
+	}
+	return fuzzy
+}
+
+// namespacesWithPod lists the other namespaces holding a pod named name, or
+// starting with it; listing pods in all namespaces may be forbidden, in which
+// case there are no suggestions
+func namespacesWithPod(ctx context.Context, clientset kubernetes.Interface, namespace, name string) []string {
+	var pods *corev1.PodList
+	err := runWithContext(ctx, func() error {
+		list, err := clientset.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{})
+		pods = list
+		return err
+	})
+	if err != nil {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	var namespaces []string
+	for _, pod := range pods.Items {
+		if pod.Namespace != namespace && !seen[pod.Namespace] && strings.HasPrefix(pod.Name, name) {
+			seen[pod.Namespace] = true
+			namespaces = append(namespaces, pod.Namespace)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// FuzzyMatch reports whether the characters of input appear in order in name,
+// ignoring case and spaces
+func FuzzyMatch(input, name string) bool {
+	name = strings.ToLower(name)
+	for _, c := range strings.ToLower(input) {
+		if unicode.IsSpace(c) {
+			continue
+		}
+		i := strings.IndexRune(name, c)
+		if i < 0 {
+			return false
+		}
+		name = name[i+1:]
+	}
+	return true
+}
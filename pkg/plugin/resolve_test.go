@@ -0,0 +1,96 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// TestResolvePod tests prefix and fuzzy resolution of pod names that weren't found
+func TestResolvePod(t *testing.T) {
+	crashing := runningPod("web-7d8f9c-xkz2q", corev1.PodRunning, false, waiting("app", "CrashLoopBackOff", 3))
+	healthyWeb := runningPod("web-7d8f9c-abcde", corev1.PodRunning, true, running("app", 0, true))
+	pendingWeb := runningPod("web-7d8f9c-pqrst", corev1.PodPending, false)
+	worker := runningPod("worker-5c6d7e-p9q8r", corev1.PodRunning, true, running("app", 0, true))
+	elsewhere := runningPod("billing-6f5e4d-m1n2o", corev1.PodRunning, true, running("app", 0, true))
+	elsewhere.Namespace = "payments"
+
+	tests := []struct {
+		name               string
+		pods               []*corev1.Pod
+		query              string
+		expectedPod        string
+		expectedCandidates []string
+		expectedNamespaces []string
+	}{
+		{
+			name:        "Only unhealthy prefix match is selected",
+			pods:        []*corev1.Pod{crashing, healthyWeb, worker},
+			query:       "web",
+			expectedPod: "web-7d8f9c-xkz2q",
+		},
+		{
+			name:        "Only match is selected even when healthy",
+			pods:        []*corev1.Pod{healthyWeb, worker},
+			query:       "wrk",
+			expectedPod: "worker-5c6d7e-p9q8r",
+		},
+		{
+			name:               "Several unhealthy matches are listed",
+			pods:               []*corev1.Pod{crashing, healthyWeb, pendingWeb, worker},
+			query:              "web-7d8f9c",
+			expectedCandidates: []string{"web-7d8f9c-abcde", "web-7d8f9c-pqrst", "web-7d8f9c-xkz2q"},
+		},
+		{
+			name:               "Other namespaces suggested",
+			pods:               []*corev1.Pod{worker, elsewhere},
+			query:              "billing",
+			expectedNamespaces: []string{"payments"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var objects []runtime.Object
+			for _, pod := range tt.pods {
+				objects = append(objects, pod)
+			}
+			clientset := newFakeClientset(nil, objects...)
+			notFound := apierrors.NewNotFound(schema.GroupResource{Resource: "pods"}, tt.query)
+
+			pod, err := resolvePod(context.Background(), clientset, testNamespace, tt.query, nil, notFound)
+
+			if tt.expectedPod != "" {
+				if err != nil || pod.Name != tt.expectedPod {
+					t.Fatalf("resolvePod() = %v, %v; want %s", pod, err, tt.expectedPod)
+				}
+				return
+			}
+
+			var notFoundErr *PodNotFoundError
+			if !errors.As(err, &notFoundErr) {
+				t.Fatalf("resolvePod() error = %v, want PodNotFoundError", err)
+			}
+			var names []string
+			for _, candidate := range notFoundErr.Candidates {
+				names = append(names, candidate.Name)
+			}
+			if !reflect.DeepEqual(names, tt.expectedCandidates) {
+				t.Errorf("resolvePod() candidates = %v, want %v", names, tt.expectedCandidates)
+			}
+			if !reflect.DeepEqual(notFoundErr.OtherNamespaces, tt.expectedNamespaces) {
+				t.Errorf("resolvePod() other namespaces = %v, want %v", notFoundErr.OtherNamespaces, tt.expectedNamespaces)
+			}
+			if !strings.HasPrefix(err.Error(), `pods "`+tt.query+`" not found`) {
+				t.Errorf("resolvePod() error = %q", err)
+			}
+		})
+	}
+}
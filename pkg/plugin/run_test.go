@@ -0,0 +1,648 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	restclient "k8s.io/client-go/rest"
+	fakerest "k8s.io/client-go/rest/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata/")
+
+const testNamespace = "triage-test"
+
+// containerLogs holds the fake log output of a single container
+// An empty error string means the log request succeeds
+type containerLogs struct {
+	Previous      string
+	Current       string
+	PreviousError string
+	CurrentError  string
+}
+
+// fakeClientset wraps the client-go fake clientset, whose GetLogs returns an
+// unusable request, and serves pod logs from a fixed map instead
+type fakeClientset struct {
+	*fake.Clientset
+	logs map[string]containerLogs
+	// denied are the permissions access reviews turn down, e.g. "list events"
+	denied map[string]bool
+}
+
+func newFakeClientset(logs map[string]containerLogs, objects ...runtime.Object) *fakeClientset {
+	c := &fakeClientset{Clientset: fake.NewSimpleClientset(objects...), logs: logs, denied: map[string]bool{}}
+	c.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		attributes := review.Spec.ResourceAttributes
+		permission := attributes.Verb + " " + attributes.Resource
+		if attributes.Subresource != "" {
+			permission += "/" + attributes.Subresource
+		}
+		review.Status.Allowed = !c.denied[permission]
+		return true, review, nil
+	})
+	return c
+}
+
+func (c *fakeClientset) CoreV1() corev1client.CoreV1Interface {
+	return &fakeCoreV1{CoreV1Interface: c.Clientset.CoreV1(), logs: c.logs}
+}
+
+type fakeCoreV1 struct {
+	corev1client.CoreV1Interface
+	logs map[string]containerLogs
+}
+
+func (c *fakeCoreV1) Pods(namespace string) corev1client.PodInterface {
+	return &fakePods{PodInterface: c.CoreV1Interface.Pods(namespace), namespace: namespace, logs: c.logs}
+}
+
+type fakePods struct {
+	corev1client.PodInterface
+	namespace string
+	logs      map[string]containerLogs
+}
+
+func (p *fakePods) GetLogs(name string, opts *corev1.PodLogOptions) *restclient.Request {
+	client := &fakerest.RESTClient{
+		NegotiatedSerializer: scheme.Codecs,
+		GroupVersion:         corev1.SchemeGroupVersion,
+		Client: fakerest.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			logs := p.logs[req.URL.Query().Get("container")]
+			body, errMsg := logs.Current, logs.CurrentError
+			if req.URL.Query().Get("previous") == "true" {
+				body, errMsg = logs.Previous, logs.PreviousError
+			}
+			if errMsg != "" {
+				return statusResponse(http.StatusBadRequest, errMsg), nil
+			}
+			// Like the API server, limitBytes keeps the start of the log
+			if limit, err := strconv.Atoi(req.URL.Query().Get("limitBytes")); err == nil && limit < len(body) {
+				body = body[:limit]
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"text/plain"}},
+				Body:       ioutil.NopCloser(strings.NewReader(body)),
+			}, nil
+		}),
+	}
+	return client.Get().
+		Namespace(p.namespace).
+		Resource("pods").
+		Name(name).
+		SubResource("log").
+		VersionedParams(opts, scheme.ParameterCodec)
+}
+
+// statusResponse builds an API error response the way the API server reports log failures
+func statusResponse(code int, message string) *http.Response {
+	status := metav1.Status{
+		TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+		Status:   metav1.StatusFailure,
+		Message:  message,
+		Reason:   metav1.StatusReasonBadRequest,
+		Code:     int32(code),
+	}
+	body, _ := json.Marshal(status)
+	return &http.Response{
+		StatusCode: code,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+	}
+}
+
+// triageScenario is one end-to-end run of RunPlugin against a fake cluster
+type triageScenario struct {
+	name   string
+	pod    *corev1.Pod
+	events []corev1.Event
+	logs   map[string]containerLogs
+	opts   TriageOptions
+	// exitCode is the exit code expected for the returned error
+	exitCode int
+}
+
+// runningPod builds a pod in the test namespace with the given container statuses
+func runningPod(name string, phase corev1.PodPhase, ready bool, statuses ...corev1.ContainerStatus) *corev1.Pod {
+	readyStatus := corev1.ConditionFalse
+	if ready {
+		readyStatus = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: testNamespace,
+			UID:       types.UID("uid-" + name),
+		},
+		Status: corev1.PodStatus{
+			Phase: phase,
+			Conditions: []corev1.PodCondition{
+				{Type: corev1.PodReady, Status: readyStatus},
+			},
+			ContainerStatuses: statuses,
+		},
+	}
+}
+
+func waiting(name, reason string, restarts int32) corev1.ContainerStatus {
+	return corev1.ContainerStatus{
+		Name:         name,
+		RestartCount: restarts,
+		State: corev1.ContainerState{
+			Waiting: &corev1.ContainerStateWaiting{Reason: reason},
+		},
+	}
+}
+
+func terminated(name, reason string, restarts int32) corev1.ContainerStatus {
+	return corev1.ContainerStatus{
+		Name:         name,
+		RestartCount: restarts,
+		State: corev1.ContainerState{
+			Terminated: &corev1.ContainerStateTerminated{Reason: reason, ExitCode: 137},
+		},
+	}
+}
+
+func running(name string, restarts int32, ready bool) corev1.ContainerStatus {
+	return corev1.ContainerStatus{
+		Name:         name,
+		RestartCount: restarts,
+		Ready:        ready,
+		State: corev1.ContainerState{
+			Running: &corev1.ContainerStateRunning{},
+		},
+	}
+}
+
+// podEvent builds an event for the pod that happened the given duration ago
+func podEvent(pod *corev1.Pod, eventType, reason, message string, ago time.Duration) corev1.Event {
+	return corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.Name + "." + strings.ToLower(reason),
+			Namespace: pod.Namespace,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			Kind:      "Pod",
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+			UID:       pod.UID,
+		},
+		Type:          eventType,
+		Reason:        reason,
+		Message:       message,
+		LastTimestamp: metav1.NewTime(time.Now().Add(-ago)),
+	}
+}
+
+// testPodsScenarios mirrors the pods in examples/test-pods.yaml
+func testPodsScenarios() []triageScenario {
+	healthyNginx := runningPod("healthy-nginx", corev1.PodRunning, true, running("nginx", 0, true))
+	crashloop := runningPod("crashloop-pod", corev1.PodRunning, false, waiting("app", "CrashLoopBackOff", 5))
+	imagePull := runningPod("imagepull-error", corev1.PodPending, false, waiting("app", "ImagePullBackOff", 0))
+	oom := runningPod("oom-pod", corev1.PodRunning, false, terminated("memory-hog", "OOMKilled", 2))
+	multi := runningPod("multi-container-pod", corev1.PodRunning, false,
+		waiting("app", "CrashLoopBackOff", 4),
+		running("sidecar", 0, true))
+	liveness := runningPod("liveness-failed", corev1.PodRunning, true, running("app", 2, true))
+	healthyMulti := runningPod("healthy-multi", corev1.PodRunning, true,
+		running("app", 0, true),
+		running("sidecar", 0, true))
+
+	crashLogs := "Starting application...\nConnecting to database...\nERROR: database connection failed\n"
+
+	return []triageScenario{
+		{
+			name: "healthy-nginx",
+			pod:  healthyNginx,
+		},
+		{
+			name: "healthy-nginx-force",
+			pod:  healthyNginx,
+			opts: TriageOptions{Force: true},
+		},
+		{
+			name:     "crashloop-pod",
+			pod:      crashloop,
+			exitCode: ExitFailing,
+			events: []corev1.Event{
+				podEvent(crashloop, corev1.EventTypeNormal, "Pulled", "Container image \"busybox:1.37\" already present on machine", time.Minute),
+				podEvent(crashloop, corev1.EventTypeWarning, "BackOff", "Back-off restarting failed container app in pod crashloop-pod", 2*time.Minute),
+			},
+			logs: map[string]containerLogs{
+				"app": {Previous: crashLogs, Current: crashLogs},
+			},
+		},
+		{
+			name:     "imagepull-error",
+			pod:      imagePull,
+			exitCode: ExitFailing,
+			events: []corev1.Event{
+				podEvent(imagePull, corev1.EventTypeWarning, "Failed", "Failed to pull image \"nonexistent-registry.io/fake-image:v1.0.0\": dial tcp: lookup nonexistent-registry.io: no such host", 5*time.Minute),
+				podEvent(imagePull, corev1.EventTypeWarning, "BackOff", "Back-off pulling image \"nonexistent-registry.io/fake-image:v1.0.0\"", 30*time.Second),
+			},
+			logs: map[string]containerLogs{
+				"app": {
+					PreviousError: `previous terminated container "app" in pod "imagepull-error" not found`,
+					CurrentError:  `container "app" in pod "imagepull-error" is waiting to start: trying and failing to pull image`,
+				},
+			},
+		},
+		{
+			name:     "imagepull-error-narrow",
+			pod:      imagePull,
+			opts:     TriageOptions{Width: 72},
+			exitCode: ExitFailing,
+			events: []corev1.Event{
+				podEvent(imagePull, corev1.EventTypeWarning, "Failed", "Failed to pull image \"nonexistent-registry.io/fake-image:v1.0.0\": dial tcp: lookup nonexistent-registry.io: no such host", 5*time.Minute),
+				podEvent(imagePull, corev1.EventTypeWarning, "BackOff", "Back-off pulling image \"nonexistent-registry.io/fake-image:v1.0.0\"", 30*time.Second),
+			},
+			logs: map[string]containerLogs{
+				"app": {
+					PreviousError: `previous terminated container "app" in pod "imagepull-error" not found`,
+					CurrentError:  `container "app" in pod "imagepull-error" is waiting to start: trying and failing to pull image`,
+				},
+			},
+		},
+		{
+			name:     "imagepull-error-compact-ascii",
+			pod:      imagePull,
+			opts:     TriageOptions{Width: 72, Compact: true, ASCII: true},
+			exitCode: ExitFailing,
+			events: []corev1.Event{
+				podEvent(imagePull, corev1.EventTypeWarning, "Failed", "Failed to pull image \"nonexistent-registry.io/fake-image:v1.0.0\": dial tcp: lookup nonexistent-registry.io: no such host", 5*time.Minute),
+				podEvent(imagePull, corev1.EventTypeWarning, "BackOff", "Back-off pulling image \"nonexistent-registry.io/fake-image:v1.0.0\"", 30*time.Second),
+			},
+			logs: map[string]containerLogs{
+				"app": {
+					PreviousError: `previous terminated container "app" in pod "imagepull-error" not found`,
+					CurrentError:  `container "app" in pod "imagepull-error" is waiting to start: trying and failing to pull image`,
+				},
+			},
+		},
+		{
+			name:     "multi-container-pod-compact",
+			pod:      multi,
+			opts:     TriageOptions{Compact: true},
+			exitCode: ExitFailing,
+			events: []corev1.Event{
+				podEvent(multi, corev1.EventTypeWarning, "BackOff", "Back-off restarting failed container app in pod multi-container-pod", time.Minute),
+			},
+			logs: map[string]containerLogs{
+				"app": {
+					Previous: "App container starting...\nConfig file not found: /etc/config/app.yaml\nERROR: failed to initialize application\n",
+					Current:  "App container starting...\n",
+				},
+			},
+		},
+		{
+			name:     "oom-pod",
+			pod:      oom,
+			exitCode: ExitFailing,
+			events: []corev1.Event{
+				podEvent(oom, corev1.EventTypeWarning, "BackOff", "Back-off restarting failed container memory-hog in pod oom-pod", 3*time.Minute),
+			},
+			logs: map[string]containerLogs{
+				"memory-hog": {
+					Previous: "Allocating memory...\nAllocated 10MB (attempt 1)\nAllocated 10MB (attempt 2)\nAllocated 10MB (attempt 3)\n",
+					Current:  "Allocating memory...\n",
+				},
+			},
+		},
+		{
+			name:     "multi-container-pod",
+			pod:      multi,
+			exitCode: ExitFailing,
+			events: []corev1.Event{
+				podEvent(multi, corev1.EventTypeWarning, "BackOff", "Back-off restarting failed container app in pod multi-container-pod", time.Minute),
+			},
+			logs: map[string]containerLogs{
+				"app": {
+					Previous: "App container starting...\nConfig file not found: /etc/config/app.yaml\nERROR: failed to initialize application\n",
+					Current:  "App container starting...\n",
+				},
+			},
+		},
+		{
+			name:     "multi-container-pod-grep",
+			pod:      multi,
+			exitCode: ExitFailing,
+			opts:     TriageOptions{Grep: "(?i)config", GrepAfter: 1},
+			logs: map[string]containerLogs{
+				"app": {
+					Previous: "App container starting...\nConfig file not found: /etc/config/app.yaml\nERROR: failed to initialize application\n",
+					Current:  "App container starting...\nLoading defaults\nConfig file not found: /etc/config/app.yaml\n",
+				},
+			},
+		},
+		{
+			name:     "multi-container-pod-markdown",
+			pod:      multi,
+			exitCode: ExitFailing,
+			opts:     TriageOptions{Output: OutputMarkdown},
+			events: []corev1.Event{
+				podEvent(multi, corev1.EventTypeWarning, "BackOff", "Back-off restarting failed container app in pod multi-container-pod", time.Minute),
+			},
+			logs: map[string]containerLogs{
+				"app": {
+					Previous: "App container starting...\nConfig file not found: /etc/config/app.yaml\nERROR: failed to initialize application\n",
+					Current:  "App container starting...\n",
+				},
+			},
+		},
+		{
+			name:     "multi-container-pod-slack",
+			pod:      multi,
+			exitCode: ExitFailing,
+			opts:     TriageOptions{Output: OutputSlack},
+			events: []corev1.Event{
+				podEvent(multi, corev1.EventTypeWarning, "BackOff", "Back-off restarting failed container app in pod <multi-container-pod>", time.Minute),
+			},
+			logs: map[string]containerLogs{
+				"app": {
+					Previous: "App container starting...\nConfig file not found: /etc/config/app.yaml\nERROR: failed to initialize application\n",
+					Current:  "App container starting...\n",
+				},
+			},
+		},
+		{
+			name:     "liveness-failed",
+			pod:      liveness,
+			exitCode: ExitDegraded,
+			events: []corev1.Event{
+				podEvent(liveness, corev1.EventTypeWarning, "Unhealthy", "Liveness probe failed: cat: can't open '/tmp/healthy': No such file or directory", 20*time.Second),
+				podEvent(liveness, corev1.EventTypeNormal, "Killing", "Container app failed liveness probe, will be restarted", 10*time.Second),
+			},
+			logs: map[string]containerLogs{
+				"app": {
+					Previous: "Starting app...\nSimulating failure - removing health file\n",
+					Current:  "Starting app...\n",
+				},
+			},
+		},
+		{
+			name: "healthy-multi-all-containers",
+			pod:  healthyMulti,
+			opts: TriageOptions{AllContainers: true},
+		},
+		{
+			name: "healthy-multi-all-containers-force",
+			pod:  healthyMulti,
+			opts: TriageOptions{AllContainers: true, Force: true},
+			logs: map[string]containerLogs{
+				"app": {
+					PreviousError: `previous terminated container "app" in pod "healthy-multi" not found`,
+					Current:       "/docker-entrypoint.sh: Configuration complete; ready for start up\n",
+				},
+				"sidecar": {
+					PreviousError: `previous terminated container "sidecar" in pod "healthy-multi" not found`,
+					Current:       "Sidecar running\nSidecar running\n",
+				},
+			},
+		},
+	}
+}
+
+// TestRunPluginGolden runs the full triage flow against a fake clientset for
+// every scenario in examples/test-pods.yaml and compares the output with testdata/
+func TestRunPluginGolden(t *testing.T) {
+	for _, sc := range testPodsScenarios() {
+		t.Run(sc.name, func(t *testing.T) {
+			objects := []runtime.Object{sc.pod}
+			for i := range sc.events {
+				objects = append(objects, &sc.events[i])
+			}
+			clientset := newFakeClientset(sc.logs, objects...)
+
+			opts := sc.opts
+			opts.PodName = sc.pod.Name
+			opts.Namespace = testNamespace
+			opts.Lines = 50
+			opts.NoColor = true
+			opts.NewClient = func(*genericclioptions.ConfigFlags) (kubernetes.Interface, error) {
+				return clientset, nil
+			}
+
+			output, runErr := runPlugin(&opts)
+			if code := ExitCode(runErr); code != sc.exitCode {
+				t.Fatalf("RunPlugin() error = %v, exit code %d; want %d", runErr, code, sc.exitCode)
+			}
+
+			assertGolden(t, filepath.Join("testdata", sc.name+".golden"), output)
+		})
+	}
+}
+
+// TestRunPluginPodNotFound checks the error returned for a missing pod
+func TestRunPluginPodNotFound(t *testing.T) {
+	clientset := newFakeClientset(nil)
+	opts := &TriageOptions{
+		PodName:   "missing",
+		Namespace: testNamespace,
+		Lines:     50,
+		NewClient: func(*genericclioptions.ConfigFlags) (kubernetes.Interface, error) {
+			return clientset, nil
+		},
+	}
+
+	err := RunPlugin(context.Background(), genericclioptions.NewConfigFlags(false), opts)
+	if err == nil || !strings.Contains(err.Error(), "failed to get pod missing in namespace triage-test") || ExitCode(err) != ExitNotFound {
+		t.Errorf("RunPlugin() error = %v, want pod not found error", err)
+	}
+}
+
+// TestRunPluginGrepLimitBytes checks that --grep without a byte cap searches
+// the whole log, and that a cap, which keeps the start of the log, is noted
+func TestRunPluginGrepLimitBytes(t *testing.T) {
+	tests := []struct {
+		name       string
+		limitBytes int64
+		want       string
+		unwanted   string
+	}{
+		{
+			name:     "whole log",
+			want:     "101: ERROR: database connection failed",
+			unwanted: "log cut at",
+		},
+		{
+			name:       "capped",
+			limitBytes: 64,
+			want:       "(log cut at 64 bytes; raise --limit-bytes to search more)",
+			unwanted:   "ERROR: database connection failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := runningPod("crashloop-pod", corev1.PodRunning, false, waiting("app", "CrashLoopBackOff", 5))
+			clientset := newFakeClientset(map[string]containerLogs{
+				"app": {Previous: strings.Repeat("retrying in 5s\n", 100) + "ERROR: database connection failed\n"},
+			}, pod)
+
+			opts := &TriageOptions{
+				PodName:    pod.Name,
+				Namespace:  testNamespace,
+				Lines:      50,
+				LimitBytes: tt.limitBytes,
+				Grep:       "ERROR",
+				NoColor:    true,
+				NewClient: func(*genericclioptions.ConfigFlags) (kubernetes.Interface, error) {
+					return clientset, nil
+				},
+			}
+
+			output, _ := runPlugin(opts)
+			if !strings.Contains(output, tt.want) {
+				t.Errorf("output lacks %q:\n%s", tt.want, output)
+			}
+			if strings.Contains(output, tt.unwanted) {
+				t.Errorf("output has %q:\n%s", tt.unwanted, output)
+			}
+		})
+	}
+}
+
+// TestRunPluginPodNames checks that several pods are triaged in the order
+// given, however long each takes, and that a missing one doesn't stop the others
+func TestRunPluginPodNames(t *testing.T) {
+	slow := runningPod("web-1", corev1.PodRunning, false, waiting("app", "CrashLoopBackOff", 4))
+	healthy := runningPod("web-2", corev1.PodRunning, true, running("app", 0, true))
+	clientset := newFakeClientset(map[string]containerLogs{
+		"app": {Previous: "ERROR: database connection failed\n"},
+	}, slow, healthy)
+	clientset.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.(k8stesting.GetAction).GetName() == slow.Name {
+			time.Sleep(100 * time.Millisecond)
+		}
+		return false, nil, nil
+	})
+
+	opts := &TriageOptions{
+		PodName:     slow.Name,
+		PodNames:    []string{"gone", healthy.Name},
+		Namespace:   testNamespace,
+		Lines:       50,
+		NoColor:     true,
+		Concurrency: 3,
+		NewClient: func(*genericclioptions.ConfigFlags) (kubernetes.Interface, error) {
+			return clientset, nil
+		},
+	}
+
+	output, runErr := runPlugin(opts)
+	if runErr == nil || !strings.Contains(runErr.Error(), "1 of 3 pods couldn't be triaged") || ExitCode(runErr) != ExitNotFound {
+		t.Errorf("RunPlugin() error = %v, exit code %d; want 1 of 3 pods not found", runErr, ExitCode(runErr))
+	}
+
+	last := -1
+	for _, want := range []string{
+		"📦 POD triage-test/web-1",
+		"ERROR: database connection failed",
+		"📦 POD triage-test/gone",
+		"❌ Couldn't triage pod 'gone': failed to get pod gone in namespace triage-test",
+		"📦 POD triage-test/web-2",
+		"✅ Pod 'web-2' is healthy",
+	} {
+		i := strings.Index(output, want)
+		if i < last {
+			t.Errorf("output has %q out of order:\n%s", want, output)
+		}
+		last = i
+	}
+}
+
+// TestRunPluginTimeout checks that a hung events list still renders the logs, marked partial
+func TestRunPluginTimeout(t *testing.T) {
+	pod := runningPod("crashloop-pod", corev1.PodRunning, false, waiting("app", "CrashLoopBackOff", 5))
+	clientset := newFakeClientset(map[string]containerLogs{
+		"app": {Previous: "ERROR: database connection failed\n"},
+	}, pod)
+
+	release := make(chan struct{})
+	defer close(release)
+	clientset.PrependReactor("list", "events", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		<-release
+		return true, &corev1.EventList{}, nil
+	})
+
+	opts := &TriageOptions{
+		PodName:   pod.Name,
+		Namespace: testNamespace,
+		Lines:     50,
+		NoColor:   true,
+		Timeout:   200 * time.Millisecond,
+		NewClient: func(*genericclioptions.ConfigFlags) (kubernetes.Interface, error) {
+			return clientset, nil
+		},
+	}
+
+	output, runErr := runPlugin(opts)
+
+	if runErr == nil || !strings.Contains(runErr.Error(), "timed out after 200ms") || ExitCode(runErr) != ExitPartial {
+		t.Errorf("RunPlugin() error = %v, want timeout error", runErr)
+	}
+	for _, want := range []string{
+		"CRITICAL EVENTS - incomplete",
+		"ERROR: database connection failed",
+		"PARTIAL RESULTS: timed out after 200ms",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+}
+
+// runPlugin runs RunPlugin against a fake cluster and returns what it wrote
+func runPlugin(opts *TriageOptions) (string, error) {
+	var out bytes.Buffer
+	opts.Out = &out
+	err := RunPlugin(context.Background(), genericclioptions.NewConfigFlags(false), opts)
+	return out.String(), err
+}
+
+// assertGolden compares output with a golden file, rewriting it when -update is set
+func assertGolden(t *testing.T, path, output string) {
+	t.Helper()
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create golden dir: %v", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(output), 0644); err != nil {
+			t.Fatalf("failed to update golden file: %v", err)
+		}
+		return
+	}
+
+	expected, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run go test -update): %v", err)
+	}
+	if string(expected) != output {
+		t.Errorf("output does not match %s\n--- got ---\n%s\n--- want ---\n%s", path, output, expected)
+	}
+}
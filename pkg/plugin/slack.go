@@ -0,0 +1,185 @@
+package plugin
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// slackMessageLimit is the length Slack shows without cutting a message
+const slackMessageLimit = 4000
+
+// slackStatus marks the pod status with a Slack emoji
+var slackStatus = map[string]string{
+	StatusHealthy:  ":large_green_circle:",
+	StatusDegraded: ":large_yellow_circle:",
+	StatusPending:  ":large_blue_circle:",
+	StatusFailing:  ":red_circle:",
+	StatusError:    ":warning:",
+}
+
+// renderSlack writes the report as Slack mrkdwn that fits in one message:
+// logs get whatever room is left after the pod status, events and next steps,
+// newest lines first
+func renderSlack(w io.Writer, report *Report) error {
+	// Measure the message without logs, then spend the rest on them
+	base := slackMessage(report, 0)
+	message := slackMessage(report, slackMessageLimit-utf8.RuneCountInString(base))
+
+	if utf8.RuneCountInString(message) > slackMessageLimit {
+		message = truncateSlackMessage(message)
+	}
+	_, err := io.WriteString(w, message)
+	return err
+}
+
+// slackWriter builds a message, tracking the room left for log lines
+type slackWriter struct {
+	strings.Builder
+	logBudget int
+}
+
+func slackMessage(report *Report, logBudget int) string {
+	s := &slackWriter{logBudget: logBudget}
+	if report.Partial != "" {
+		fmt.Fprintf(s, ":hourglass: *Partial results:* %s before collection finished\n\n", slackEscape(report.Partial))
+	}
+	for i, pod := range report.Pods {
+		if i > 0 {
+			s.WriteString("\n")
+		}
+		s.writePod(pod)
+	}
+	return s.String()
+}
+
+func (s *slackWriter) writePod(pod PodReport) {
+	fmt.Fprintf(s, "%s *Pod `%s` in `%s` is %s*\n", slackStatus[pod.Status], slackEscape(pod.Name), slackEscape(pod.Namespace), pod.Status)
+	if pod.Status == StatusError {
+		s.WriteString("Couldn't triage the pod: " + slackEscape(pod.Message) + "\n")
+		return
+	}
+	fmt.Fprintf(s, "Phase: %s | Ready: %s | Restarts: %d\n", pod.Phase, pod.Ready, pod.Restarts)
+	if pod.Skipped {
+		return
+	}
+	if pod.Message != "" {
+		s.WriteString(slackEscape(pod.Message) + "\n")
+	}
+
+	if len(pod.Events) > 0 {
+		s.WriteString("\n*Events*\n")
+		for i, event := range pod.Events {
+			if i == maxEvents {
+				break
+			}
+			fmt.Fprintf(s, "• %s ago %s *%s*: %s\n",
+				formatDuration(time.Since(event.Timestamp).Round(time.Second)),
+				event.Type, slackEscape(event.Reason), slackEscape(strings.Join(strings.Fields(event.Message), " ")))
+		}
+	} else if pod.EventsError != "" {
+		fmt.Fprintf(s, "\n_Events not fetched: %s_\n", slackEscape(pod.EventsError))
+	}
+
+	for _, container := range pod.triagedContainers() {
+		fmt.Fprintf(s, "\n*Container `%s`*: %s, %d restarts\n", slackEscape(container.Name), container.Status(), container.RestartCount)
+
+		if container.Crash != nil {
+			s.writeLogBlock(fmt.Sprintf("Crash (%s)", container.Crash.Language), logLineTexts(container.CrashLines))
+		}
+		s.writeLogSection("Previous logs", container.Previous)
+		s.writeLogSection("Current logs", container.Current)
+	}
+
+	if others := pod.otherContainers(); len(others) > 0 {
+		s.WriteString("\nOther containers: " + slackEscape(strings.Join(others, ", ")) + "\n")
+	}
+
+	if len(pod.NextSteps) > 0 {
+		s.WriteString("\n*Next steps*\n")
+		for _, step := range pod.NextSteps {
+			s.WriteString("• " + slackEscape(step.Title) + "\n")
+			if len(step.Commands) > 0 {
+				s.WriteString("```" + slackCode(strings.Join(step.Commands, "\n")) + "```\n")
+			}
+		}
+	}
+
+	if pod.Redacted > 0 {
+		fmt.Fprintf(s, "\n_Redacted %d item(s): %s_\n", pod.Redacted, slackEscape(pod.RedactedSummary))
+	}
+}
+
+func (s *slackWriter) writeLogSection(title string, section LogSection) {
+	if section.Empty() {
+		return
+	}
+	if section.Error != "" && !section.Incomplete {
+		fmt.Fprintf(s, "_%s not available: %s_\n", title, slackEscape(section.Error))
+		return
+	}
+	if section.Incomplete {
+		title += ", incomplete"
+	}
+	s.writeLogBlock(fmt.Sprintf("%s (%s)", title, section.Window), logLineTexts(section.Lines))
+}
+
+// writeLogBlock writes the newest lines that fit the log budget in a code block
+func (s *slackWriter) writeLogBlock(title string, lines []string) {
+	s.WriteString("_" + slackEscape(title) + "_\n")
+
+	// Six backticks and a newline frame the block
+	room := s.logBudget - 7
+	start := len(lines)
+	for start > 0 {
+		size := utf8.RuneCountInString(slackCode(sanitizeLine(lines[start-1]))) + 1
+		if size > room {
+			break
+		}
+		room -= size
+		start--
+	}
+
+	if start < len(lines) {
+		shown := make([]string, len(lines)-start)
+		for i, line := range lines[start:] {
+			shown[i] = slackCode(sanitizeLine(line))
+		}
+		block := "```" + strings.Join(shown, "\n") + "```\n"
+		s.WriteString(block)
+		s.logBudget -= utf8.RuneCountInString(block)
+	}
+	if start > 0 {
+		fmt.Fprintf(s, "_(%d earlier line(s) left out to fit a Slack message)_\n", start)
+	}
+}
+
+// truncateSlackMessage cuts a message that is too long even without logs at a
+// line boundary
+func truncateSlackMessage(message string) string {
+	const note = "\n_… cut to fit a Slack message_\n"
+	runes := []rune(message)
+	cut := string(runes[:slackMessageLimit-utf8.RuneCountInString(note)])
+	if i := strings.LastIndex(cut, "\n"); i > 0 {
+		cut = cut[:i]
+	}
+	// Close a code block left open by the cut
+	if strings.Count(cut, "```")%2 == 1 {
+		cut += "```"
+	}
+	return cut + note
+}
+
+// slackEscaper escapes the characters Slack reads as control sequences
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func slackEscape(text string) string {
+	return slackEscaper.Replace(sanitizeLine(text))
+}
+
+// slackCode escapes text for a code block, which ends at the next "```"
+func slackCode(text string) string {
+	return strings.Replace(slackEscaper.Replace(text), "```", "`\u200b``", -1)
+}
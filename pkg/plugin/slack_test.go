@@ -0,0 +1,78 @@
+package plugin
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// TestRenderSlackFitsMessage checks that long logs are cut to the newest lines
+// that fit in one Slack message
+func TestRenderSlackFitsMessage(t *testing.T) {
+	var lines []LogLine
+	for i := 1; i <= 500; i++ {
+		lines = append(lines, LogLine{Text: fmt.Sprintf("line %03d: request failed with status 503", i), Count: 1})
+	}
+	report := &Report{Pods: []PodReport{{
+		Name:      "api-1",
+		Namespace: "prod",
+		Phase:     "Running",
+		Ready:     "0/1",
+		Status:    StatusFailing,
+		Containers: []ContainerReport{{
+			Name:     "app",
+			Reason:   "CrashLoopBackOff",
+			Triaged:  true,
+			Previous: LogSection{Window: "Last 500 lines", Lines: lines},
+		}},
+		NextSteps: []NextStep{{Title: "Roll back", Commands: []string{"kubectl rollout undo deployment/api -n prod"}}},
+	}}}
+
+	var out bytes.Buffer
+	if err := renderSlack(&out, report); err != nil {
+		t.Fatal(err)
+	}
+	message := out.String()
+
+	if n := utf8.RuneCountInString(message); n > slackMessageLimit {
+		t.Errorf("message is %d characters, want at most %d", n, slackMessageLimit)
+	}
+	for _, want := range []string{"line 500:", "earlier line(s) left out", "kubectl rollout undo"} {
+		if !strings.Contains(message, want) {
+			t.Errorf("message lacks %q:\n%s", want, message)
+		}
+	}
+	if strings.Contains(message, "line 001:") {
+		t.Errorf("message keeps the oldest lines:\n%s", message)
+	}
+}
+
+func TestTruncateSlackMessage(t *testing.T) {
+	message := "*Next steps*\n```" + strings.Repeat("kubectl get pods\n", 400) + "```\n"
+
+	got := truncateSlackMessage(message)
+	if n := utf8.RuneCountInString(got); n > slackMessageLimit {
+		t.Errorf("truncated message is %d characters, want at most %d", n, slackMessageLimit)
+	}
+	if strings.Count(got, "```")%2 != 0 {
+		t.Errorf("truncated message leaves a code block open:\n%s", got)
+	}
+}
+
+func TestSlackCode(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "a < b && c > d", want: "a &lt; b &amp;&amp; c &gt; d"},
+		{input: "run ```make```", want: "run `\u200b``make`\u200b``"},
+	}
+
+	for _, tt := range tests {
+		if got := slackCode(tt.input); got != tt.want {
+			t.Errorf("slackCode(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
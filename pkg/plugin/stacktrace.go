@@ -0,0 +1,299 @@
+package plugin
+
+import (
+	"regexp"
+	"strings"
+)
+
+// crashScanLines is how much of the previous log is fetched to find a stack
+// trace that started before the --lines tail window
+const crashScanLines int64 = 1000
+
+// maxCrashLines caps the CRASH section so a deep trace can't take over the screen
+const maxCrashLines = 60
+
+// StackTrace is a crash stack trace extracted from a container log
+type StackTrace struct {
+	Language string
+	Lines    []string
+	// AppFrame is the index in Lines of the first application frame, -1 if none was found
+	AppFrame int
+	// OtherGoroutines counts the goroutines of a Go dump left out after the crashing one
+	OtherGoroutines int
+}
+
+var (
+	goPanicStart    = regexp.MustCompile(`^(panic: |fatal error: )`)
+	goTraceLine     = regexp.MustCompile(`^(\s|goroutine \d+ \[|created by |panic: |\[signal |\[recovered\]|exit status \d+$|[\w./\-*()\[\]{}]+\(.*\)$)`)
+	goGoroutine     = regexp.MustCompile(`^goroutine \d+ \[`)
+	goFrameFunc     = regexp.MustCompile(`^[\w./\-*()\[\]{}]+\(.*\)$`)
+	atFrame         = regexp.MustCompile(`^\s+at \S`)
+	atTraceLine     = regexp.MustCompile(`^\s+at \S|^\s*\.\.\. \d+ (more|common frames omitted)|^\s*Caused by: |^\s*Suppressed: |^\s*---> |^\s*--- End of `)
+	dotnetFrame     = regexp.MustCompile(`\.cs:line \d+$|^\s+at (System|Microsoft)\.`)
+	nodeFrame       = regexp.MustCompile(`:\d+:\d+\)?$|\(node:|\(internal/|<anonymous>`)
+	pythonStart     = regexp.MustCompile(`^Traceback \(most recent call last\):`)
+	pythonChain     = regexp.MustCompile(`^(During handling of the above exception|The above exception was the direct cause)`)
+	pythonFrame     = regexp.MustCompile(`^\s+File "([^"]+)", line \d+`)
+	rustPanicStart  = regexp.MustCompile(`^thread '.*' panicked at`)
+	rustTraceLine   = regexp.MustCompile(`^(note: |stack backtrace:|\s+\d+: |\s+at )`)
+	rustFrame       = regexp.MustCompile(`^\s+\d+: (\S+)`)
+	goLibraryFrames = []string{"runtime.", "panic(", "testing.", "reflect.", "net/http.", "sync.", "created by runtime"}
+	javaLibraries   = []string{"java.", "javax.", "jdk.", "sun.", "com.sun.", "kotlin.", "kotlinx.", "scala.", "org.springframework.", "org.apache.", "io.netty.", "com.fasterxml.", "org.hibernate.", "reactor.", "io.micrometer.", "org.junit."}
+	nodeLibraries   = []string{"node:", "internal/", "/node_modules/", "<anonymous>"}
+	dotnetLibraries = []string{"System.", "Microsoft."}
+	pythonLibraries = []string{"/site-packages/", "/dist-packages/", "/lib/python", "<frozen "}
+	rustLibraries   = []string{"std::", "core::", "alloc::", "rust_begin_unwind", "__rust", "<unknown>"}
+)
+
+// extractStackTrace finds the last stack trace in a log
+// Go panics, Java/Kotlin exceptions (with Caused by chains), Python tracebacks,
+// Node.js errors, Rust panics and .NET exceptions are recognised
+func extractStackTrace(logs string) *StackTrace {
+	lines := strings.Split(strings.Replace(logs, "\r\n", "\n", -1), "\n")
+
+	var last *StackTrace
+	for i := 0; i < len(lines); i++ {
+		trace, end := matchStackTrace(lines, i)
+		if trace == nil {
+			continue
+		}
+		last = trace
+		i = end - 1
+	}
+
+	return last
+}
+
+// window returns the lines of the trace to show: at most maxCrashLines,
+// keeping the first application frame in view
+func (t *StackTrace) window() (start, end int) {
+	start, end = 0, len(t.Lines)
+	if end > maxCrashLines {
+		if t.AppFrame >= maxCrashLines {
+			start = t.AppFrame - maxCrashLines/2
+		}
+		end = start + maxCrashLines
+		if end > len(t.Lines) {
+			end = len(t.Lines)
+			start = end - maxCrashLines
+		}
+	}
+	return start, end
+}
+
+// matchStackTrace tries every detector at line i, returning the trace and the index after it
+func matchStackTrace(lines []string, i int) (*StackTrace, int) {
+	switch {
+	case goPanicStart.MatchString(lines[i]):
+		return goStackTrace(lines, i)
+	case pythonStart.MatchString(lines[i]):
+		return pythonStackTrace(lines, i)
+	case rustPanicStart.MatchString(lines[i]):
+		return rustStackTrace(lines, i)
+	case i+1 < len(lines) && !atFrame.MatchString(lines[i]) && atFrame.MatchString(lines[i+1]):
+		return atStackTrace(lines, i)
+	}
+	return nil, i + 1
+}
+
+// goStackTrace keeps the panic message and the crashing goroutine of a Go dump
+func goStackTrace(lines []string, start int) (*StackTrace, int) {
+	end := start + 1
+	for end < len(lines) && (lines[end] == "" || goTraceLine.MatchString(lines[end])) {
+		end++
+	}
+
+	trace := &StackTrace{Language: "Go panic", AppFrame: -1}
+	goroutines := 0
+	for _, line := range trimTrailingBlank(lines[start:end]) {
+		if goGoroutine.MatchString(line) {
+			goroutines++
+		}
+		if goroutines > 1 {
+			if goGoroutine.MatchString(line) {
+				trace.OtherGoroutines++
+			}
+			continue
+		}
+		trace.Lines = append(trace.Lines, line)
+	}
+	trace.Lines = trimTrailingBlank(trace.Lines)
+
+	for i, line := range trace.Lines {
+		if goGoroutine.MatchString(line) || !goFrameFunc.MatchString(line) || hasAnyPrefix(line, goLibraryFrames) {
+			continue
+		}
+		// The next line holds the file path of the frame
+		if i+1 < len(trace.Lines) && (strings.Contains(trace.Lines[i+1], "/pkg/mod/") || strings.Contains(trace.Lines[i+1], "/go/src/")) {
+			continue
+		}
+		trace.AppFrame = i
+		break
+	}
+
+	return trace, end
+}
+
+// pythonStackTrace collects a traceback, following "During handling..." chains
+func pythonStackTrace(lines []string, start int) (*StackTrace, int) {
+	end := start
+	for end < len(lines) && pythonStart.MatchString(lines[end]) {
+		end++
+		for end < len(lines) && (strings.HasPrefix(lines[end], " ") || strings.HasPrefix(lines[end], "\t")) {
+			end++
+		}
+		// The exception line closes the traceback
+		if end < len(lines) {
+			end++
+		}
+
+		// A chained exception starts another traceback after a note and blank lines
+		next := end
+		for next < len(lines) && lines[next] == "" {
+			next++
+		}
+		if next < len(lines) && pythonChain.MatchString(lines[next]) {
+			next++
+			for next < len(lines) && lines[next] == "" {
+				next++
+			}
+			if next < len(lines) && pythonStart.MatchString(lines[next]) {
+				end = next
+				continue
+			}
+		}
+		break
+	}
+
+	trace := &StackTrace{Language: "Python traceback", Lines: lines[start:end], AppFrame: -1}
+
+	// Tracebacks list the most recent call last, so the frame closest to the
+	// crash is the last one outside the standard library and site-packages
+	for i := len(trace.Lines) - 1; i >= 0; i-- {
+		m := pythonFrame.FindStringSubmatch(trace.Lines[i])
+		if m != nil && !containsAny(m[1], pythonLibraries) {
+			trace.AppFrame = i
+			break
+		}
+	}
+
+	return trace, end
+}
+
+// rustStackTrace collects a Rust panic message and its backtrace
+func rustStackTrace(lines []string, start int) (*StackTrace, int) {
+	end := start + 1
+	// Since Rust 1.73 the panic message is on its own line after the location
+	if strings.HasSuffix(lines[start], ":") && end < len(lines) {
+		end++
+	}
+	for end < len(lines) && rustTraceLine.MatchString(lines[end]) {
+		end++
+	}
+
+	trace := &StackTrace{Language: "Rust panic", Lines: lines[start:end], AppFrame: -1}
+	for i, line := range trace.Lines {
+		m := rustFrame.FindStringSubmatch(line)
+		if m != nil && !hasAnyPrefix(m[1], rustLibraries) {
+			trace.AppFrame = i
+			break
+		}
+	}
+
+	return trace, end
+}
+
+// atStackTrace collects an exception header followed by "at ..." frames, the
+// shape shared by Java, Node.js and .NET traces
+func atStackTrace(lines []string, header int) (*StackTrace, int) {
+	// .NET prints inner exceptions as "---> Type: message" lines above the frames
+	start := header
+	for start > 0 && strings.HasPrefix(strings.TrimSpace(lines[start]), "---> ") {
+		start--
+	}
+
+	// Caused by: and inner exception headers are followed by frames of their own
+	end := header + 1
+	for end < len(lines) && atTraceLine.MatchString(lines[end]) {
+		end++
+	}
+
+	trace := &StackTrace{Lines: lines[start:end], AppFrame: -1}
+
+	libraries := javaLibraries
+	switch {
+	case dotnetFrame.MatchString(lines[header+1]) || strings.Contains(lines[start], "Unhandled exception."):
+		trace.Language = ".NET exception"
+		libraries = dotnetLibraries
+	case nodeFrame.MatchString(lines[header+1]):
+		trace.Language = "Node.js error"
+		libraries = nodeLibraries
+	default:
+		trace.Language = "Java exception"
+	}
+
+	for i, line := range trace.Lines {
+		if !atFrame.MatchString(line) {
+			continue
+		}
+		frame := strings.TrimPrefix(strings.TrimSpace(line), "at ")
+		if trace.Language == "Node.js error" {
+			if !containsAny(frame, libraries) {
+				trace.AppFrame = i
+				break
+			}
+			continue
+		}
+		if !hasAnyPrefix(frame, libraries) {
+			trace.AppFrame = i
+			break
+		}
+	}
+
+	return trace, end
+}
+
+// trimTrailingBlank drops empty lines at the end of a slice
+func trimTrailingBlank(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(s string, substrs []string) bool {
+	for _, substr := range substrs {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// lastLines returns the last n lines of a log
+func lastLines(logs string, n int64) string {
+	if n <= 0 {
+		return logs
+	}
+
+	trimmed := strings.TrimRight(logs, "\n")
+	count := int64(0)
+	for i := len(trimmed) - 1; i >= 0; i-- {
+		if trimmed[i] == '\n' {
+			count++
+			if count == n {
+				return logs[i+1:]
+			}
+		}
+	}
+	return logs
+}
@@ -0,0 +1,190 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestExtractStackTrace tests stack trace detection for each supported runtime
+func TestExtractStackTrace(t *testing.T) {
+	tests := []struct {
+		name          string
+		logs          string
+		expectedLang  string
+		expectedFirst string
+		expectedLast  string
+		expectedApp   string
+	}{
+		{
+			name: "Go panic with goroutine dump",
+			logs: `Starting server on :8080
+panic: runtime error: invalid memory address or nil pointer dereference
+[signal SIGSEGV: segmentation violation code=0x1 addr=0x0 pc=0x4a1b2c]
+
+goroutine 1 [running]:
+github.com/acme/api/internal/db.(*Pool).Get(0x0)
+	/go/pkg/mod/github.com/acme/api@v1.2.0/internal/db/pool.go:31 +0x1c
+main.connect(...)
+	/app/main.go:42 +0x3e
+main.main()
+	/app/main.go:17 +0x25
+
+goroutine 6 [select]:
+net/http.(*Server).Serve(0xc000132000)
+	/usr/local/go/src/net/http/server.go:3056 +0x3a5
+exit status 2`,
+			expectedLang:  "Go panic",
+			expectedFirst: "panic: runtime error: invalid memory address or nil pointer dereference",
+			expectedLast:  "\t/app/main.go:17 +0x25",
+			expectedApp:   "main.connect(...)",
+		},
+		{
+			name: "Java exception with Caused by chain and logger prefix",
+			logs: `2024-05-01 12:00:00 INFO  Starting OrderService
+2024-05-01 12:00:01 ERROR Application run failed
+java.lang.IllegalStateException: Failed to execute CommandLineRunner
+	at org.springframework.boot.SpringApplication.callRunner(SpringApplication.java:798)
+	at com.acme.orders.OrderApplication.main(OrderApplication.java:12)
+Caused by: java.sql.SQLException: Connection refused
+	at org.postgresql.Driver.connect(Driver.java:285)
+	at com.acme.orders.db.Repository.open(Repository.java:40)
+	... 5 more
+2024-05-01 12:00:02 INFO  Shutting down`,
+			expectedLang:  "Java exception",
+			expectedFirst: "java.lang.IllegalStateException: Failed to execute CommandLineRunner",
+			expectedLast:  "\t... 5 more",
+			expectedApp:   "\tat com.acme.orders.OrderApplication.main(OrderApplication.java:12)",
+		},
+		{
+			name: "Python traceback with chained exception",
+			logs: `INFO:root:booting
+Traceback (most recent call last):
+  File "/app/worker.py", line 10, in load
+    return json.load(f)
+  File "/usr/lib/python3.11/json/__init__.py", line 293, in load
+    return loads(fp.read())
+json.decoder.JSONDecodeError: Expecting value: line 1 column 1 (char 0)
+
+During handling of the above exception, another exception occurred:
+
+Traceback (most recent call last):
+  File "/app/worker.py", line 22, in <module>
+    config = load()
+  File "/usr/local/lib/python3.11/site-packages/click/core.py", line 1157, in __call__
+    return self.main(*args, **kwargs)
+RuntimeError: invalid config
+exiting`,
+			expectedLang:  "Python traceback",
+			expectedFirst: "Traceback (most recent call last):",
+			expectedLast:  "RuntimeError: invalid config",
+			expectedApp:   `  File "/app/worker.py", line 22, in <module>`,
+		},
+		{
+			name: "Node.js error",
+			logs: `Server listening on 3000
+TypeError: Cannot read properties of undefined (reading 'id')
+    at handler (/app/src/routes/users.js:14:22)
+    at Layer.handle [as handle_request] (/app/node_modules/express/lib/router/layer.js:95:5)
+    at process.processTicksAndRejections (node:internal/process/task_queues:95:5)`,
+			expectedLang:  "Node.js error",
+			expectedFirst: "TypeError: Cannot read properties of undefined (reading 'id')",
+			expectedLast:  "    at process.processTicksAndRejections (node:internal/process/task_queues:95:5)",
+			expectedApp:   "    at handler (/app/src/routes/users.js:14:22)",
+		},
+		{
+			name: "Rust panic with backtrace",
+			logs: `thread 'main' panicked at src/main.rs:7:5:
+called ` + "`Option::unwrap()`" + ` on a ` + "`None`" + ` value
+stack backtrace:
+   0: rust_begin_unwind
+             at /rustc/abc/library/std/src/panicking.rs:645:5
+   1: core::panicking::panic
+             at /rustc/abc/library/core/src/panicking.rs:144:5
+   2: server::load_config
+             at ./src/main.rs:7:5
+note: Some details are omitted, run with ` + "`RUST_BACKTRACE=full`" + ` for a verbose backtrace.`,
+			expectedLang:  "Rust panic",
+			expectedFirst: "thread 'main' panicked at src/main.rs:7:5:",
+			expectedLast:  "note: Some details are omitted, run with `RUST_BACKTRACE=full` for a verbose backtrace.",
+			expectedApp:   "   2: server::load_config",
+		},
+		{
+			name: ".NET exception with inner exception",
+			logs: `Unhandled exception. System.InvalidOperationException: Startup failed
+ ---> System.IO.FileNotFoundException: Could not find file '/app/appsettings.json'.
+   at System.IO.FileStream.ValidateFileHandle(SafeFileHandle fileHandle)
+   at Acme.Api.Program.LoadSettings() in /src/Program.cs:line 21
+   --- End of inner exception stack trace ---
+   at Acme.Api.Program.Main(String[] args) in /src/Program.cs:line 9`,
+			expectedLang:  ".NET exception",
+			expectedFirst: "Unhandled exception. System.InvalidOperationException: Startup failed",
+			expectedLast:  "   at Acme.Api.Program.Main(String[] args) in /src/Program.cs:line 9",
+			expectedApp:   "   at Acme.Api.Program.LoadSettings() in /src/Program.cs:line 21",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trace := extractStackTrace(tt.logs)
+			if trace == nil {
+				t.Fatalf("extractStackTrace() = nil, want %s", tt.expectedLang)
+			}
+			if trace.Language != tt.expectedLang {
+				t.Errorf("extractStackTrace() language = %v, want %v", trace.Language, tt.expectedLang)
+			}
+			if trace.Lines[0] != tt.expectedFirst {
+				t.Errorf("extractStackTrace() first line = %q, want %q", trace.Lines[0], tt.expectedFirst)
+			}
+			if last := trace.Lines[len(trace.Lines)-1]; last != tt.expectedLast {
+				t.Errorf("extractStackTrace() last line = %q, want %q", last, tt.expectedLast)
+			}
+			if trace.AppFrame < 0 || trace.Lines[trace.AppFrame] != tt.expectedApp {
+				t.Errorf("extractStackTrace() app frame = %d, want %q", trace.AppFrame, tt.expectedApp)
+			}
+		})
+	}
+}
+
+// TestExtractStackTraceNone tests that ordinary error logs are not mistaken for traces
+func TestExtractStackTraceNone(t *testing.T) {
+	logs := "Starting application...\nConnecting to database...\nERROR: database connection failed\n"
+	if trace := extractStackTrace(logs); trace != nil {
+		t.Errorf("extractStackTrace() = %+v, want nil", trace)
+	}
+}
+
+// TestGoroutineDumpTrimmed tests that only the crashing goroutine is kept
+func TestGoroutineDumpTrimmed(t *testing.T) {
+	logs := "panic: boom\n\ngoroutine 1 [running]:\nmain.main()\n\t/app/main.go:5 +0x1\n\n" +
+		"goroutine 2 [chan receive]:\nmain.worker()\n\t/app/main.go:9 +0x1\n\n" +
+		"goroutine 3 [IO wait]:\nmain.reader()\n\t/app/main.go:12 +0x1\n"
+
+	trace := extractStackTrace(logs)
+	if trace == nil || trace.OtherGoroutines != 2 || len(trace.Lines) != 5 {
+		t.Errorf("extractStackTrace() = %+v, want 5 lines and 2 other goroutines", trace)
+	}
+}
+
+// TestCollectLogsFindsTraceBeforeTail tests that a trace older than the tail window is still extracted
+func TestCollectLogsFindsTraceBeforeTail(t *testing.T) {
+	var previous strings.Builder
+	previous.WriteString("Exception in thread \"main\" java.lang.OutOfMemoryError: Java heap space\n")
+	previous.WriteString("\tat com.acme.cache.Loader.fill(Loader.java:88)\n")
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&previous, "shutdown hook %d\n", i)
+	}
+
+	clientset := newFakeClientset(map[string]containerLogs{
+		"app": {Previous: previous.String()},
+	})
+	results := collectLogs(context.Background(), clientset, nil, testNamespace, "crashloop-pod", []ContainerInfo{{Name: "app"}}, 5, 0)
+
+	if results[0].Crash == nil || results[0].Crash.Language != "Java exception" {
+		t.Fatalf("collectLogs() crash = %+v, want Java exception", results[0].Crash)
+	}
+	if got := strings.Count(results[0].Previous, "\n"); got != 5 {
+		t.Errorf("collectLogs() previous has %d lines, want 5", got)
+	}
+}
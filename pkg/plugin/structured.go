@@ -0,0 +1,282 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Normalised log levels, in increasing severity
+const (
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelError = "error"
+	LevelFatal = "fatal"
+)
+
+var levelRank = map[string]int{
+	LevelDebug: 0,
+	LevelInfo:  1,
+	LevelWarn:  2,
+	LevelError: 3,
+	LevelFatal: 4,
+}
+
+// LogKeys lists the field names looked up in structured log lines, in order
+type LogKeys struct {
+	Level   []string `mapstructure:"level"`
+	Message []string `mapstructure:"message"`
+	Error   []string `mapstructure:"error"`
+	Time    []string `mapstructure:"time"`
+}
+
+// DefaultLogKeys covers the field names of the common JSON and logfmt loggers
+// (zap, logrus, slog, zerolog, pino, bunyan, ECS, GCP and log4j2 JSON layouts)
+var DefaultLogKeys = LogKeys{
+	Level:   []string{"level", "lvl", "severity", "loglevel", "log.level", "levelname"},
+	Message: []string{"msg", "message", "event", "log"},
+	Error:   []string{"error", "err", "exception", "error.message", "stacktrace"},
+	Time:    []string{"time", "ts", "timestamp", "@timestamp", "t"},
+}
+
+// WithDefaults returns keys tried first, followed by DefaultLogKeys
+func (k LogKeys) WithDefaults() LogKeys {
+	return LogKeys{
+		Level:   append(append([]string{}, k.Level...), DefaultLogKeys.Level...),
+		Message: append(append([]string{}, k.Message...), DefaultLogKeys.Message...),
+		Error:   append(append([]string{}, k.Error...), DefaultLogKeys.Error...),
+		Time:    append(append([]string{}, k.Time...), DefaultLogKeys.Time...),
+	}
+}
+
+// structuredLine is a JSON or logfmt log line broken into its common fields
+type structuredLine struct {
+	Level   string
+	Message string
+	Error   string
+	Time    string
+	Fields  map[string]string
+}
+
+// parseStructuredLine recognises JSON objects and logfmt lines that carry at
+// least a level or a message under one of the configured keys
+func parseStructuredLine(line string, keys LogKeys) (*structuredLine, bool) {
+	trimmed := strings.TrimSpace(line)
+
+	var fields map[string]string
+	if strings.HasPrefix(trimmed, "{") && strings.HasSuffix(trimmed, "}") {
+		fields = parseJSONFields(trimmed)
+	} else {
+		fields = parseLogfmtFields(trimmed)
+	}
+	if fields == nil {
+		return nil, false
+	}
+
+	parsed := &structuredLine{Fields: fields}
+	rawLevel := takeField(fields, keys.Level)
+	parsed.Message = takeField(fields, keys.Message)
+	parsed.Error = takeField(fields, keys.Error)
+	parsed.Time = takeField(fields, keys.Time)
+	if rawLevel == "" && parsed.Message == "" {
+		return nil, false
+	}
+	parsed.Level = normalizeLevel(rawLevel)
+
+	return parsed, true
+}
+
+// parseJSONFields flattens a JSON object into dotted keys with string values
+func parseJSONFields(line string) map[string]string {
+	decoder := json.NewDecoder(strings.NewReader(line))
+	decoder.UseNumber()
+
+	var object map[string]interface{}
+	if err := decoder.Decode(&object); err != nil {
+		return nil
+	}
+
+	fields := map[string]string{}
+	flattenJSON("", object, fields)
+	return fields
+}
+
+func flattenJSON(prefix string, object map[string]interface{}, fields map[string]string) {
+	for key, value := range object {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			flattenJSON(key, v, fields)
+		case string:
+			fields[key] = v
+		case nil:
+			fields[key] = "null"
+		default:
+			encoded, _ := json.Marshal(v)
+			fields[key] = string(encoded)
+		}
+	}
+}
+
+// parseLogfmtFields parses key=value pairs, returning nil unless the whole
+// line is made of at least two of them
+func parseLogfmtFields(line string) map[string]string {
+	fields := map[string]string{}
+	for i := 0; i < len(line); {
+		if line[i] == ' ' {
+			i++
+			continue
+		}
+
+		keyStart := i
+		for i < len(line) && isLogfmtKeyChar(line[i]) {
+			i++
+		}
+		if i == keyStart || i >= len(line) || line[i] != '=' {
+			return nil
+		}
+		key := line[keyStart:i]
+		i++
+
+		var value string
+		if i < len(line) && line[i] == '"' {
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return nil
+			}
+			unquoted, err := strconv.Unquote(line[i : end+1])
+			if err != nil {
+				return nil
+			}
+			value = unquoted
+			i = end + 1
+		} else {
+			valueStart := i
+			for i < len(line) && line[i] != ' ' {
+				i++
+			}
+			value = line[valueStart:i]
+		}
+		fields[key] = value
+	}
+
+	if len(fields) < 2 {
+		return nil
+	}
+	return fields
+}
+
+func isLogfmtKeyChar(c byte) bool {
+	return c == '_' || c == '.' || c == '-' || c == '@' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// takeField removes and returns the first of keys present in fields
+func takeField(fields map[string]string, keys []string) string {
+	for _, key := range keys {
+		if value, ok := fields[key]; ok {
+			delete(fields, key)
+			return value
+		}
+	}
+	return ""
+}
+
+// normalizeLevel maps the many spellings of log levels, including numeric
+// bunyan/pino levels, onto the five normalised ones; unknown levels map to ""
+func normalizeLevel(level string) string {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "trace", "debug", "dbg", "d", "10", "20":
+		return LevelDebug
+	case "info", "information", "informational", "notice", "i", "30":
+		return LevelInfo
+	case "warn", "warning", "w", "40":
+		return LevelWarn
+	case "error", "err", "e", "50":
+		return LevelError
+	case "fatal", "critical", "crit", "panic", "dpanic", "emerg", "emergency", "alert", "f", "60":
+		return LevelFatal
+	}
+	return ""
+}
+
+// levelAtLeast reports whether level is as severe as min; lines without a known level always pass
+func levelAtLeast(level, min string) bool {
+	if level == "" || min == "" {
+		return true
+	}
+	return levelRank[level] >= levelRank[min]
+}
+
+// render formats a structured line as "15:04:05.000 ERROR message error=... key=value"
+func (s *structuredLine) render() string {
+	var b bytes.Buffer
+	if s.Time != "" {
+		b.WriteString(formatLogTime(s.Time))
+		b.WriteString(" ")
+	}
+
+	level := strings.ToUpper(s.Level)
+	if level == "" {
+		level = "-"
+	}
+	fmt.Fprintf(&b, "%-5s %s", level, s.Message)
+
+	if s.Error != "" {
+		b.WriteString(" error=" + quoteLogfmt(s.Error))
+	}
+
+	keys := make([]string, 0, len(s.Fields))
+	for key := range s.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		b.WriteString(" " + key + "=" + quoteLogfmt(s.Fields[key]))
+	}
+
+	return strings.TrimRight(b.String(), " ")
+}
+
+// formatLogTime shortens RFC 3339 and epoch timestamps to a time of day
+func formatLogTime(value string) string {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t.UTC().Format("15:04:05.000")
+	}
+
+	if epoch, err := strconv.ParseFloat(value, 64); err == nil {
+		unit := time.Second
+		switch {
+		case epoch > 1e17:
+			unit = time.Nanosecond
+		case epoch > 1e14:
+			unit = time.Microsecond
+		case epoch > 1e11:
+			unit = time.Millisecond
+		}
+		return time.Unix(0, int64(epoch*float64(unit))).UTC().Format("15:04:05.000")
+	}
+
+	return value
+}
+
+// quoteLogfmt quotes values that would otherwise be ambiguous in key=value form
+func quoteLogfmt(value string) string {
+	if value == "" || strings.ContainsAny(value, " \"=\t") {
+		return strconv.Quote(value)
+	}
+	return value
+}
@@ -0,0 +1,115 @@
+package plugin
+
+import (
+	"testing"
+)
+
+// TestParseStructuredLine tests JSON and logfmt detection and rendering
+func TestParseStructuredLine(t *testing.T) {
+	tests := []struct {
+		name          string
+		line          string
+		keys          LogKeys
+		expectedOK    bool
+		expectedLevel string
+		expectedText  string
+	}{
+		{
+			name:          "zap JSON with error field",
+			line:          `{"level":"error","ts":1714564800.5,"msg":"request failed","error":"dial tcp 10.0.0.1:5432: connection refused","path":"/api"}`,
+			expectedOK:    true,
+			expectedLevel: LevelError,
+			expectedText:  `12:00:00.500 ERROR request failed error="dial tcp 10.0.0.1:5432: connection refused" path=/api`,
+		},
+		{
+			name:          "Info JSON mentioning error is not an error",
+			line:          `{"level":"info","time":"2024-05-01T12:00:00Z","msg":"retrying","last_error":"timeout"}`,
+			expectedOK:    true,
+			expectedLevel: LevelInfo,
+			expectedText:  `12:00:00.000 INFO  retrying last_error=timeout`,
+		},
+		{
+			name:          "Nested ECS level and numeric fields",
+			line:          `{"@timestamp":"2024-05-01T12:00:00.123Z","log":{"level":"WARN"},"message":"slow query","duration_ms":1500}`,
+			expectedOK:    true,
+			expectedLevel: LevelWarn,
+			expectedText:  `12:00:00.123 WARN  slow query duration_ms=1500`,
+		},
+		{
+			name:          "pino numeric level",
+			line:          `{"level":60,"time":1714564800000,"msg":"out of memory"}`,
+			expectedOK:    true,
+			expectedLevel: LevelFatal,
+			expectedText:  `12:00:00.000 FATAL out of memory`,
+		},
+		{
+			name:          "logfmt with quoted values",
+			line:          `time=2024-05-01T12:00:00Z level=warning msg="cache miss" key=user:42`,
+			expectedOK:    true,
+			expectedLevel: LevelWarn,
+			expectedText:  `12:00:00.000 WARN  cache miss key=user:42`,
+		},
+		{
+			name:          "Custom level key",
+			line:          `{"sev":"E","text":"boom"}`,
+			keys:          LogKeys{Level: []string{"sev"}, Message: []string{"text"}},
+			expectedOK:    true,
+			expectedLevel: LevelError,
+			expectedText:  `ERROR boom`,
+		},
+		{
+			name:       "Plain text line",
+			line:       "ERROR: database connection failed",
+			expectedOK: false,
+		},
+		{
+			name:       "JSON without level or message",
+			line:       `{"status":200,"bytes":512}`,
+			expectedOK: false,
+		},
+		{
+			name:       "Text with a single key=value",
+			line:       "Listening on port=8080",
+			expectedOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, ok := parseStructuredLine(tt.line, tt.keys.WithDefaults())
+			if ok != tt.expectedOK {
+				t.Fatalf("parseStructuredLine() ok = %v, want %v", ok, tt.expectedOK)
+			}
+			if !ok {
+				return
+			}
+			if parsed.Level != tt.expectedLevel {
+				t.Errorf("parseStructuredLine() level = %v, want %v", parsed.Level, tt.expectedLevel)
+			}
+			if text := parsed.render(); text != tt.expectedText {
+				t.Errorf("render() = %q, want %q", text, tt.expectedText)
+			}
+		})
+	}
+}
+
+// TestLevelAtLeast tests the --min-level filter
+func TestLevelAtLeast(t *testing.T) {
+	tests := []struct {
+		level    string
+		min      string
+		expected bool
+	}{
+		{LevelInfo, LevelWarn, false},
+		{LevelWarn, LevelWarn, true},
+		{LevelFatal, LevelError, true},
+		{"", LevelError, true},
+		{LevelDebug, "", true},
+	}
+
+	for _, tt := range tests {
+		if result := levelAtLeast(tt.level, tt.min); result != tt.expected {
+			t.Errorf("levelAtLeast(%q, %q) = %v, want %v", tt.level, tt.min, result, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// templateFuncs are available to -o go-template on top of the built-in functions
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+	"ago": func(t time.Time) string {
+		return formatDuration(time.Since(t).Round(time.Second))
+	},
+}
+
+// goTemplateRenderer executes a Go template against the report, as
+// kubectl -o go-template does against an object
+func goTemplateRenderer(text string) (Renderer, error) {
+	if text == "" {
+		return nil, fmt.Errorf("-o go-template needs a template, e.g. go-template='{{range .Pods}}{{.Name}} {{.Status}}{{\"\\n\"}}{{end}}'")
+	}
+	tmpl, err := template.New("output").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	return RendererFunc(func(w io.Writer, report *Report) error {
+		return tmpl.Execute(w, report)
+	}), nil
+}
+
+// column is one column of -o custom-columns
+type column struct {
+	header string
+	path   *jsonpath.JSONPath
+}
+
+// customColumnsRenderer prints a row per pod, with the columns given as
+// HEADER:path pairs where path is a JSONPath into the pod report, e.g.
+// "POD:.Name,STATUS:.Status,REASON:.Containers[0].Reason"
+func customColumnsRenderer(spec string) (Renderer, error) {
+	if spec == "" {
+		return nil, fmt.Errorf("-o custom-columns needs columns, e.g. custom-columns=POD:.Name,STATUS:.Status")
+	}
+
+	var columns []column
+	for _, part := range strings.Split(spec, ",") {
+		colon := strings.Index(part, ":")
+		if colon <= 0 || colon == len(part)-1 {
+			return nil, fmt.Errorf("custom column %q isn't of the form HEADER:path", part)
+		}
+		header, path := part[:colon], part[colon+1:]
+		if !strings.HasPrefix(path, "{") {
+			path = "{" + path + "}"
+		}
+
+		jp := jsonpath.New(header).AllowMissingKeys(true)
+		if err := jp.Parse(path); err != nil {
+			return nil, fmt.Errorf("invalid path in custom column %q: %w", part, err)
+		}
+		columns = append(columns, column{header: header, path: jp})
+	}
+
+	return RendererFunc(func(w io.Writer, report *Report) error {
+		tw := tabwriter.NewWriter(w, 6, 4, 3, ' ', 0)
+		headers := make([]string, len(columns))
+		for i, col := range columns {
+			headers[i] = col.header
+		}
+		fmt.Fprintln(tw, strings.Join(headers, "\t"))
+
+		for _, pod := range report.Pods {
+			// Paths address the report as kubectl addresses objects: as JSON
+			var data interface{}
+			raw, err := json.Marshal(pod)
+			if err != nil {
+				return err
+			}
+			decoder := json.NewDecoder(bytes.NewReader(raw))
+			decoder.UseNumber()
+			if err := decoder.Decode(&data); err != nil {
+				return err
+			}
+
+			cells := make([]string, len(columns))
+			for i, col := range columns {
+				cells[i], err = columnValue(col.path, data)
+				if err != nil {
+					return fmt.Errorf("custom column %s: %w", col.header, err)
+				}
+			}
+			fmt.Fprintln(tw, strings.Join(cells, "\t"))
+		}
+		return tw.Flush()
+	}), nil
+}
+
+// columnValue joins the values a path finds with commas, or returns "<none>"
+func columnValue(path *jsonpath.JSONPath, data interface{}) (string, error) {
+	results, err := path.FindResults(data)
+	if err != nil {
+		return "", err
+	}
+
+	var values []string
+	for _, result := range results {
+		for _, value := range result {
+			if value.IsValid() && value.CanInterface() && value.Interface() != nil {
+				values = append(values, fmt.Sprint(value.Interface()))
+			}
+		}
+	}
+	if len(values) == 0 {
+		return "<none>", nil
+	}
+	return strings.Join(values, ","), nil
+}
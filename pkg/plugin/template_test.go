@@ -0,0 +1,97 @@
+package plugin
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func templateTestReport() *Report {
+	return &Report{Pods: []PodReport{
+		{
+			Name:     "api-1",
+			Status:   StatusFailing,
+			Restarts: 4,
+			Containers: []ContainerReport{
+				{Name: "app", Reason: "CrashLoopBackOff", Triaged: true},
+				{Name: "sidecar", State: "Running"},
+			},
+		},
+		{Name: "api-2", Status: StatusHealthy, Skipped: true},
+	}}
+}
+
+func TestNewRendererTemplates(t *testing.T) {
+	templateFile := filepath.Join(t.TempDir(), "summary.tmpl")
+	if err := ioutil.WriteFile(templateFile, []byte(`{{range .Pods}}{{.Name}}={{.Status}};{{end}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		output  string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "go-template",
+			output: `go-template={{range .Pods}}{{.Name}} {{.Status}} {{.Restarts}}{{"\n"}}{{end}}`,
+			want:   "api-1 failing 4\napi-2 healthy 0\n",
+		},
+		{
+			name:   "go-template calling methods",
+			output: `go-template={{range .Pods}}{{range .Containers}}{{.Name}}:{{.Status}} {{end}}{{end}}`,
+			want:   "app:CrashLoopBackOff sidecar:Running ",
+		},
+		{
+			name:   "go-template-file",
+			output: "go-template-file=" + templateFile,
+			want:   "api-1=failing;api-2=healthy;",
+		},
+		{
+			name:   "custom-columns",
+			output: "custom-columns=POD:.Name,STATUS:.Status,RESTARTS:.Restarts,REASON:.Containers[0].Reason",
+			want: "POD     STATUS    RESTARTS   REASON\n" +
+				"api-1   failing   4          CrashLoopBackOff\n" +
+				"api-2   healthy   0          <none>\n",
+		},
+		{
+			name:   "custom-columns with braces and wildcards",
+			output: "custom-columns=POD:{.Name},CONTAINERS:.Containers[*].Name",
+			want: "POD     CONTAINERS\n" +
+				"api-1   app,sidecar\n" +
+				"api-2   <none>\n",
+		},
+		{name: "unknown format", output: "yaml", wantErr: `unknown -o "yaml"`},
+		{name: "empty template", output: "go-template=", wantErr: "needs a template"},
+		{name: "broken template", output: "go-template={{.Name", wantErr: "failed to parse template"},
+		{name: "missing template file", output: "go-template-file=" + filepath.Join(t.TempDir(), "missing"), wantErr: "failed to read template"},
+		{name: "column without path", output: "custom-columns=POD", wantErr: "HEADER:path"},
+		{name: "broken column path", output: "custom-columns=POD:.Containers[", wantErr: "invalid path"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			renderer, err := newRenderer(&TriageOptions{Output: tt.output})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("newRenderer(%q) error = %v, want %q", tt.output, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newRenderer(%q) error = %v", tt.output, err)
+			}
+
+			var out bytes.Buffer
+			if err := renderer.Render(&out, templateTestReport()); err != nil {
+				t.Fatalf("render error = %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package plugin
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Lc-Lin/kubectl-triage/pkg/logger"
+)
+
+// Kinds of API calls summed up by an apiTrace
+const (
+	callPods   = "pods"
+	callEvents = "events"
+	callLogs   = "logs"
+	callOther  = "other"
+)
+
+// apiTrace reports each Kubernetes API request made through its transport:
+// the call, its status, latency and bytes, and sums them up per kind of call
+// so a slow triage shows whether events or log streams are the bottleneck
+type apiTrace struct {
+	log *logger.Logger
+
+	mu    sync.Mutex
+	calls map[string]*apiCalls
+}
+
+// apiCalls sums up the requests of one kind
+type apiCalls struct {
+	Count  int
+	Errors int
+	Bytes  int64
+	// Latency adds up the time of each request, so it can exceed the wall
+	// clock when requests run in parallel
+	Latency time.Duration
+}
+
+func newAPITrace(log *logger.Logger) *apiTrace {
+	return &apiTrace{log: log, calls: map[string]*apiCalls{}}
+}
+
+// wrap is a transport.WrapperFunc that traces the requests going through rt
+func (t *apiTrace) wrap(rt http.RoundTripper) http.RoundTripper {
+	return &tracingTransport{trace: t, next: rt}
+}
+
+type tracingTransport struct {
+	trace *apiTrace
+	next  http.RoundTripper
+}
+
+func (rt *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := rt.next.RoundTrip(req)
+	if err != nil {
+		rt.trace.record(req, 0, time.Since(start), 0, 0, err)
+		return nil, err
+	}
+
+	// Log streams take as long as their body: the request ends when it's closed
+	firstByte := time.Since(start)
+	resp.Body = &tracedBody{ReadCloser: resp.Body, done: func(n int64, readErr error) {
+		rt.trace.record(req, resp.StatusCode, time.Since(start), firstByte, n, readErr)
+	}}
+	return resp, nil
+}
+
+// tracedBody counts the bytes read from a response and reports them once,
+// at EOF or when the body is closed
+type tracedBody struct {
+	io.ReadCloser
+	n    int64
+	once sync.Once
+	done func(n int64, err error)
+}
+
+func (b *tracedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	if err == io.EOF {
+		b.once.Do(func() { b.done(b.n, nil) })
+	} else if err != nil {
+		b.once.Do(func() { b.done(b.n, err) })
+	}
+	return n, err
+}
+
+func (b *tracedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.done(b.n, nil) })
+	return err
+}
+
+// record logs a finished request and adds it to the sums of its kind
+func (t *apiTrace) record(req *http.Request, status int, latency, firstByte time.Duration, bytes int64, err error) {
+	kind := callKind(req.URL.Path)
+
+	t.mu.Lock()
+	calls := t.calls[kind]
+	if calls == nil {
+		calls = &apiCalls{}
+		t.calls[kind] = calls
+	}
+	calls.Count++
+	calls.Bytes += bytes
+	calls.Latency += latency
+	if err != nil || status >= 400 {
+		calls.Errors++
+	}
+	t.mu.Unlock()
+
+	call := req.Method + " " + req.URL.Path
+	if req.URL.RawQuery != "" {
+		call += "?" + req.URL.RawQuery
+	}
+	var result string
+	switch {
+	case status == 0:
+		result = fmt.Sprintf("failed after %s: %v", roundDuration(latency), err)
+	case kind == callLogs:
+		result = fmt.Sprintf("%d in %s (first byte %s, %s)", status, roundDuration(latency), roundDuration(firstByte), formatBytes(bytes))
+	default:
+		result = fmt.Sprintf("%d in %s (%s)", status, roundDuration(latency), formatBytes(bytes))
+	}
+	if err != nil && status != 0 {
+		result += fmt.Sprintf(", cut short: %v", err)
+	}
+	t.log.Verbose("API %s %s", call, result)
+}
+
+// callKind sorts a request path into pods, events, logs or other calls
+func callKind(path string) string {
+	switch {
+	case strings.HasSuffix(path, "/log"):
+		return callLogs
+	case strings.Contains(path, "/events"):
+		return callEvents
+	case strings.Contains(path, "/pods"):
+		return callPods
+	}
+	return callOther
+}
+
+// summary describes the calls of each kind, e.g.
+// "events 1 call(s) in 840ms (2.1 KB); logs 4 call(s) in 1.9s (182.0 KB)"
+func (t *apiTrace) summary() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	kinds := make([]string, 0, len(t.calls))
+	for kind := range t.calls {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	var parts []string
+	for _, kind := range kinds {
+		calls := t.calls[kind]
+		part := fmt.Sprintf("%s %d call(s) in %s (%s)", kind, calls.Count, roundDuration(calls.Latency), formatBytes(calls.Bytes))
+		if calls.Errors > 0 {
+			part += fmt.Sprintf(", %d failed", calls.Errors)
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return "no API calls"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// runTimings is the wall-clock breakdown of a triage run
+type runTimings struct {
+	start time.Time
+	// lookup is the time spent finding the pods, triage collecting them and
+	// render writing the output
+	lookup time.Duration
+	triage time.Duration
+	render time.Duration
+	// trace sums up the API calls; nil when they aren't traced
+	trace *apiTrace
+}
+
+// report writes the breakdown at LevelVerbose
+func (t *runTimings) report(log *logger.Logger) {
+	total := time.Since(t.start)
+	other := total - t.lookup - t.triage - t.render
+	if other < 0 {
+		other = 0
+	}
+	log.Verbose("Took %s: finding pods %s, collecting %s, rendering %s, other %s",
+		roundDuration(total), roundDuration(t.lookup), roundDuration(t.triage), roundDuration(t.render), roundDuration(other))
+	if t.trace != nil {
+		log.Verbose("API calls: %s", t.trace.summary())
+	}
+}
+
+// logPodTimings reports how long a pod took to triage, and whether the
+// events list or the log streams held it up
+func logPodTimings(log *logger.Logger, result *podTriage) {
+	pod := result.Pod
+	if result.Err != nil {
+		log.Verbose("Couldn't triage pod %s/%s in %s: %v", pod.Namespace, pod.Name, roundDuration(result.Duration), result.Err)
+		return
+	}
+	if result.Skipped {
+		log.Verbose("Triaged pod %s/%s in %s: healthy, skipped", pod.Namespace, pod.Name, roundDuration(result.Duration))
+		return
+	}
+	log.Verbose("Triaged pod %s/%s in %s (events %s, logs of %d container(s) %s, in parallel): %s",
+		pod.Namespace, pod.Name, roundDuration(result.Duration), roundDuration(result.EventsDuration),
+		len(result.Logs), roundDuration(result.LogsDuration), outcomeStatus(result.Outcome))
+}
+
+// roundDuration rounds a duration for timing reports: to the millisecond,
+// or to 10ms above a second
+func roundDuration(d time.Duration) time.Duration {
+	if d > time.Second {
+		return d.Round(10 * time.Millisecond)
+	}
+	return d.Round(time.Millisecond)
+}
+
+// formatBytes formats a byte count in B, KB or MB
+func formatBytes(n int64) string {
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%d B", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	}
+	return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+}
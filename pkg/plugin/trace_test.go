@@ -0,0 +1,84 @@
+package plugin
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Lc-Lin/kubectl-triage/pkg/logger"
+)
+
+// TestAPITrace checks that each request is reported and summed up by kind
+func TestAPITrace(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/log"):
+			io.WriteString(w, strings.Repeat("x", 2048))
+		case strings.HasSuffix(r.URL.Path, "/events"):
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			io.WriteString(w, "{}")
+		}
+	}))
+	defer server.Close()
+
+	var diagnostics bytes.Buffer
+	trace := newAPITrace(logger.New(io.Discard, &diagnostics, logger.LevelVerbose))
+	client := &http.Client{Transport: trace.wrap(http.DefaultTransport)}
+	for _, path := range []string{
+		"/api/v1/namespaces/prod/pods/web-1",
+		"/api/v1/namespaces/prod/events",
+		"/api/v1/namespaces/prod/pods/web-1/log",
+	} {
+		resp, err := client.Get(server.URL + path + "?container=app")
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	for _, want := range []string{
+		"API GET /api/v1/namespaces/prod/pods/web-1?container=app 200 in ",
+		"API GET /api/v1/namespaces/prod/events?container=app 403 in ",
+		"(first byte ",
+		"2.0 KB)",
+	} {
+		if !strings.Contains(diagnostics.String(), want) {
+			t.Errorf("diagnostics lack %q:\n%s", want, diagnostics.String())
+		}
+	}
+	if n := strings.Count(diagnostics.String(), "\n"); n != 3 {
+		t.Errorf("got %d lines, want one per request:\n%s", n, diagnostics.String())
+	}
+
+	summary := trace.summary()
+	for _, want := range []string{"events 1 call(s)", ", 1 failed", "logs 1 call(s)", "(2.0 KB)", "pods 1 call(s)"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary() = %q, lacks %q", summary, want)
+		}
+	}
+}
+
+// TestCallKind tests sorting request paths into kinds of calls
+func TestCallKind(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/v1/namespaces/prod/pods/web-1", callPods},
+		{"/api/v1/namespaces/prod/pods", callPods},
+		{"/api/v1/namespaces/prod/pods/web-1/log", callLogs},
+		{"/api/v1/namespaces/prod/events", callEvents},
+		{"/api/v1/namespaces", callOther},
+	}
+
+	for _, tt := range tests {
+		if got := callKind(tt.path); got != tt.want {
+			t.Errorf("callKind(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}